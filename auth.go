@@ -0,0 +1,225 @@
+package sim_board
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const defaultInviteTTL = 24 * time.Hour
+
+var errInvalidSession = newError("会话密钥无效")
+
+type roomAuth struct {
+	lock         sync.RWMutex
+	passwordHash []byte
+	inviteSecret []byte
+	banned       map[string]struct{}
+	sessions     map[string]string
+}
+
+func newInviteSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}
+
+func (a *roomAuth) setPassword(password string) error {
+	var hash []byte
+	if password != "" {
+		var err error
+		hash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.passwordHash = hash
+	return nil
+}
+
+func (a *roomAuth) revokeInvites() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.inviteSecret = newInviteSecret()
+}
+
+func (a *roomAuth) ban(player string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.banned == nil {
+		a.banned = make(map[string]struct{})
+	}
+	a.banned[player] = struct{}{}
+}
+
+func (a *roomAuth) unban(player string) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	_, ok := a.banned[player]
+	delete(a.banned, player)
+	return ok
+}
+
+func (a *roomAuth) isBanned(player string) bool {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	_, ok := a.banned[player]
+	return ok
+}
+
+func (a *roomAuth) issueSession(player string) string {
+	session := base64.RawURLEncoding.EncodeToString(newInviteSecret()[:24])
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.sessions == nil {
+		a.sessions = make(map[string]string)
+	}
+	a.sessions[player] = session
+	return session
+}
+
+func (a *roomAuth) dropSession(player string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	delete(a.sessions, player)
+}
+
+func (a *roomAuth) verifySession(player, session string) bool {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	expected, ok := a.sessions[player]
+	return ok && hmac.Equal([]byte(session), []byte(expected))
+}
+
+func (a *roomAuth) sign(room string, expiresAt int64) string {
+	mac := hmac.New(sha256.New, a.inviteSecret)
+	_, _ = fmt.Fprintf(mac, "%s|%d", room, expiresAt)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (a *roomAuth) createInvite(room string, ttl time.Duration) (string, time.Time) {
+	expiresAt := time.Now().Add(ttl)
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	sig := a.sign(room, expiresAt.Unix())
+	return fmt.Sprintf("%d.%s", expiresAt.Unix(), sig), expiresAt
+}
+
+func (a *roomAuth) verifyInvite(room, token string) bool {
+	expStr, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	exp, err := strconv.ParseInt(expStr, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return hmac.Equal([]byte(sig), []byte(a.sign(room, exp)))
+}
+
+func (r *Room) Authorize(player, password, invite string) error {
+	if r.auth.isBanned(player) {
+		return newError("你已被禁止进入该房间")
+	}
+	if invite != "" {
+		if r.auth.verifyInvite(r.name, invite) {
+			return nil
+		}
+		return newError("邀请链接无效或已过期")
+	}
+	r.auth.lock.RLock()
+	hash := r.auth.passwordHash
+	r.auth.lock.RUnlock()
+	if hash == nil {
+		return nil
+	}
+	if password == "" {
+		return newError("该房间需要密码")
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+		return newError("密码错误")
+	}
+	return nil
+}
+
+func (r *Room) authorizeFrom(ip, player, password, invite string) error {
+	if !authFailures.ready(ip) {
+		return errThrottled
+	}
+	err := r.Authorize(player, password, invite)
+	if err != nil {
+		authFailures.allow(ip)
+	}
+	return err
+}
+
+func (r *Room) requireHost(player string) error {
+	if player != r.host {
+		return newError("仅房主可以执行此操作")
+	}
+	return nil
+}
+
+type SetPasswordArgs struct {
+	Password string `json:"password"`
+}
+
+func (r *Room) handleSetPassword(player string, args SetPasswordArgs) (any, error) {
+	if err := r.requireHost(player); err != nil {
+		return nil, err
+	}
+	if err := r.auth.setPassword(args.Password); err != nil {
+		return nil, newError("设置密码失败：%v", err.Error())
+	}
+	return nil, nil
+}
+
+type CreateInviteArgs struct {
+	TTL int64 `json:"ttl"`
+}
+
+type InviteResponse struct {
+	Room      string    `json:"room"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (r *Room) handleCreateInvite(player string, args CreateInviteArgs) (any, error) {
+	if err := r.requireHost(player); err != nil {
+		return nil, err
+	}
+	ttl := defaultInviteTTL
+	if args.TTL > 0 {
+		ttl = time.Duration(args.TTL) * time.Second
+	}
+	token, expiresAt := r.auth.createInvite(r.name, ttl)
+	ret := &InviteResponse{
+		Room:      r.name,
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}
+	r.sendMsgTo(player, &ServerMessage{Type: "invite", Data: ret})
+	return ret, nil
+}
+
+func (r *Room) handleRevokeInvites(player string) (any, error) {
+	if err := r.requireHost(player); err != nil {
+		return nil, err
+	}
+	r.auth.revokeInvites()
+	return nil, nil
+}
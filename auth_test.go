@@ -0,0 +1,64 @@
+package sim_board
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func testSession(t *testing.T, res *CommandResult) string {
+	t.Helper()
+	var joined JoinResult
+	if err := json.Unmarshal(res.Result, &joined); err != nil || joined.Session == "" {
+		t.Fatalf("join returned no session: %s", res.Result)
+	}
+	return joined.Session
+}
+
+func TestSessionRequired(t *testing.T) {
+	r := newTestRoom(t, "host")
+	res, err := r.join(context.Background(), &joinQuitMsg{player: "alice", lang: DefaultLang, verify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := testSession(t, res)
+
+	tests := []struct {
+		name    string
+		player  string
+		session string
+		ok      bool
+	}{
+		{"missing session", "alice", "", false},
+		{"wrong session", "alice", "guess", false},
+		{"other player's session", "host", session, false},
+		{"matching session", "alice", session, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.join(context.Background(), &joinQuitMsg{player: tt.player, lang: DefaultLang, session: tt.session, verify: true})
+			if tt.ok != (err == nil) {
+				t.Fatalf("join err = %v, want ok = %v", err, tt.ok)
+			}
+			if err != nil && !errors.Is(err, errInvalidSession) {
+				t.Fatalf("join err = %v, want invalid session", err)
+			}
+			_, err = r.Request(context.Background(), &ClientMessage{Command: "state", Player: tt.player, Room: r.name, session: tt.session, verify: true})
+			if tt.ok != (err == nil) {
+				t.Fatalf("request err = %v, want ok = %v", err, tt.ok)
+			}
+		})
+	}
+
+	if _, err = testDo(r, "alice", "leave", LeaveArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	res, err = r.join(context.Background(), &joinQuitMsg{player: "alice", lang: DefaultLang, session: session, verify: true})
+	if err != nil {
+		t.Fatalf("rejoin after leaving: %v", err)
+	}
+	if testSession(t, res) == session {
+		t.Fatal("session was reused after the player left")
+	}
+}
@@ -0,0 +1,328 @@
+package sim_board
+
+import (
+	"slices"
+	"sort"
+)
+
+type ValuedDeck interface {
+	Value(card Card) (int, bool)
+	Denominations() []Card // in any order
+	Count(card Card) int
+	Take(card Card, n int) []Card
+}
+
+type ChipsResult struct {
+	Amount int              `json:"amount"`
+	Chips  map[DeckCard]int `json:"chips"`
+}
+
+type TotalsResult struct {
+	Deck    int            `json:"deck"`
+	Players map[string]int `json:"players"`
+	Zones   map[string]int `json:"zones"`
+	Board   int            `json:"board"`
+	Pot     int            `json:"pot"`
+}
+
+func (r *Room) valuedDeck(id int) (*roomDeck, ValuedDeck, error) {
+	d, ok := r.deck(id)
+	if !ok {
+		return nil, nil, newError("牌堆不存在")
+	}
+	vd, ok := d.Deck.(ValuedDeck)
+	if !ok {
+		return nil, nil, newError("该牌堆不支持面值操作")
+	}
+	return d, vd, nil
+}
+
+func makeChange(vd ValuedDeck, amount int, avail func(Card) int, allow func(Card) bool) (map[Card]int, bool) {
+	denoms := slices.Clone(vd.Denominations())
+	sort.SliceStable(denoms, func(i, j int) bool {
+		vi, _ := vd.Value(denoms[i])
+		vj, _ := vd.Value(denoms[j])
+		return vi > vj
+	})
+	plan := make(map[Card]int)
+	for _, c := range denoms {
+		if allow != nil && !allow(c) {
+			continue
+		}
+		v, ok := vd.Value(c)
+		if !ok || v <= 0 {
+			continue
+		}
+		if n := min(avail(c), amount/v); n > 0 {
+			plan[c] = n
+			amount -= n * v
+		}
+	}
+	return plan, amount == 0
+}
+
+func (r *Room) takeChips(d *roomDeck, vd ValuedDeck, plan map[Card]int, to map[DeckCard]int) map[DeckCard]int {
+	ret := make(map[DeckCard]int, len(plan))
+	for c, n := range plan {
+		for _, card := range vd.Take(c, n) {
+			to[DeckCard{DeckId: d.id, Card: card}]++
+			ret[DeckCard{DeckId: d.id, Card: card}]++
+		}
+	}
+	d.version++
+	return ret
+}
+
+func (r *Room) chipValue(vd ValuedDeck, cards map[DeckCard]int, deck int) int {
+	total := 0
+	for card, cnt := range cards {
+		if card.DeckId != deck {
+			continue
+		}
+		if v, ok := vd.Value(card.Card); ok {
+			total += v * cnt
+		}
+	}
+	return total
+}
+
+type WithdrawArgs struct {
+	Deck   int    `json:"deck"`
+	Amount int    `json:"amount"`
+	Target string `json:"target"`
+}
+
+func (r *Room) handleWithdraw(player string, args WithdrawArgs) (any, error) {
+	d, vd, err := r.valuedDeck(args.Deck)
+	if err != nil {
+		return nil, err
+	}
+	if args.Amount <= 0 {
+		return nil, newError("金额无效")
+	}
+	if args.Target == "" {
+		args.Target = player
+	}
+	hole, ok := r.hole[args.Target]
+	if !ok {
+		return nil, newError("目标不存在")
+	}
+	plan, ok := makeChange(vd, args.Amount, vd.Count, nil)
+	if !ok {
+		return nil, newError("无法凑出该金额：%d", args.Amount)
+	}
+	ret := &ChipsResult{Amount: args.Amount, Chips: r.takeChips(d, vd, plan, hole)}
+	r.touchHand(args.Target)
+	r.broadcast()
+	return ret, nil
+}
+
+type ExchangeArgs struct {
+	Deck int          `json:"deck"`
+	Give map[Card]int `json:"give"`
+	Want []Card       `json:"want"`
+}
+
+func (r *Room) handleExchange(player string, args ExchangeArgs) (any, error) {
+	d, vd, err := r.valuedDeck(args.Deck)
+	if err != nil {
+		return nil, err
+	}
+	hole := r.hole[player]
+	amount := 0
+	for c, n := range args.Give {
+		v, ok := vd.Value(c)
+		if !ok || n <= 0 {
+			return nil, newError("面值无效：%s", c)
+		}
+		if hole[DeckCard{DeckId: d.id, Card: c}] < n {
+			return nil, newError("手牌余量不足")
+		}
+		amount += v * n
+	}
+	if amount == 0 {
+		return nil, newError("金额无效")
+	}
+	allow := func(c Card) bool {
+		if len(args.Want) > 0 {
+			return SliceContains(args.Want, c)
+		}
+		return args.Give[c] == 0
+	}
+	plan, ok := makeChange(vd, amount, func(c Card) int {
+		return vd.Count(c) + args.Give[c]
+	}, allow)
+	if !ok {
+		return nil, newError("无法兑换")
+	}
+	for c, n := range args.Give {
+		card := DeckCard{DeckId: d.id, Card: c}
+		if hole[card] -= n; hole[card] == 0 {
+			delete(hole, card)
+		}
+		for i := 0; i < n; i++ {
+			d.Return(c)
+		}
+	}
+	ret := &ChipsResult{Amount: amount, Chips: r.takeChips(d, vd, plan, hole)}
+	r.touchHand(player)
+	r.broadcast()
+	return ret, nil
+}
+
+type BetArgs struct {
+	Deck   int `json:"deck"`
+	Amount int `json:"amount"`
+}
+
+func (r *Room) handleBet(player string, args BetArgs) (any, error) {
+	d, vd, err := r.valuedDeck(args.Deck)
+	if err != nil {
+		return nil, err
+	}
+	if args.Amount <= 0 {
+		return nil, newError("金额无效")
+	}
+	hole := r.hole[player]
+	plan, ok := makeChange(vd, args.Amount, func(c Card) int {
+		return hole[DeckCard{DeckId: d.id, Card: c}]
+	}, nil)
+	if !ok {
+		return nil, newError("无法凑出该金额：%d", args.Amount)
+	}
+	ret := &ChipsResult{Amount: args.Amount, Chips: make(map[DeckCard]int, len(plan))}
+	for c, n := range plan {
+		card := DeckCard{DeckId: d.id, Card: c}
+		if hole[card] -= n; hole[card] == 0 {
+			delete(hole, card)
+		}
+		r.pot[card] += n
+		ret.Chips[card] = n
+	}
+	r.touchHand(player)
+	r.broadcast()
+	return ret, nil
+}
+
+type AwardArgs struct {
+	Deck    int      `json:"deck"`
+	Players []string `json:"players"`
+}
+
+type AwardResult struct {
+	Share int `json:"share"`
+	Rest  int `json:"rest"`
+}
+
+func (r *Room) handleAward(player string, args AwardArgs) (any, error) {
+	if err := r.requireHost(player); err != nil {
+		return nil, err
+	}
+	d, vd, err := r.valuedDeck(args.Deck)
+	if err != nil {
+		return nil, err
+	}
+	if len(args.Players) == 0 {
+		return nil, newError("目标不存在")
+	}
+	for i, p := range args.Players {
+		if _, ok := r.hole[p]; !ok {
+			return nil, newError("目标不存在")
+		}
+		if SliceContains(args.Players[:i], p) {
+			return nil, newError("玩家重复：%s", p)
+		}
+	}
+	total := r.chipValue(vd, r.pot, d.id)
+	if total == 0 {
+		return nil, newError("奖池为空")
+	}
+	share, rest := total/len(args.Players), total%len(args.Players)
+	avail := make(map[Card]int)
+	for _, c := range vd.Denominations() {
+		avail[c] = vd.Count(c) + r.pot[DeckCard{DeckId: d.id, Card: c}]
+	}
+	amounts := make([]int, 0, len(args.Players)+1)
+	for range args.Players {
+		amounts = append(amounts, share)
+	}
+	amounts = append(amounts, rest)
+	plans := make([]map[Card]int, 0, len(amounts))
+	for _, amount := range amounts {
+		plan, ok := makeChange(vd, amount, func(c Card) int { return avail[c] }, nil)
+		if !ok {
+			return nil, newError("无法凑出该金额：%d", amount)
+		}
+		for c, n := range plan {
+			avail[c] -= n
+		}
+		plans = append(plans, plan)
+	}
+	for card, cnt := range r.pot {
+		if card.DeckId != d.id {
+			continue
+		}
+		for i := 0; i < cnt; i++ {
+			d.Return(card.Card)
+		}
+		delete(r.pot, card)
+	}
+	for i, p := range args.Players {
+		r.takeChips(d, vd, plans[i], r.hole[p])
+		r.touchHand(p)
+	}
+	r.takeChips(d, vd, plans[len(args.Players)], r.pot)
+	r.broadcast()
+	return &AwardResult{Share: share, Rest: rest}, nil
+}
+
+func (r *Room) handleTotals(player string, args DeckArgs) (any, error) {
+	d, vd, err := r.valuedDeck(args.Deck)
+	if err != nil {
+		return nil, err
+	}
+	ret := &TotalsResult{
+		Deck:    d.id,
+		Players: make(map[string]int, len(r.players)),
+		Zones:   make(map[string]int, len(r.zones)),
+		Pot:     r.chipValue(vd, r.pot, d.id),
+	}
+	for _, p := range r.players {
+		ret.Players[p] = r.chipValue(vd, r.hole[p], d.id)
+	}
+	for _, z := range r.zones {
+		if z.visibleTo(player) {
+			ret.Zones[z.ID] = 0
+		}
+	}
+	for _, card := range r.board {
+		if card.Card.DeckId != d.id {
+			continue
+		}
+		if z, ok := r.zone(card.Zone); ok && !z.visibleTo(player) {
+			continue
+		}
+		v, _ := vd.Value(card.Card.Card)
+		ret.Board += v
+		if card.Zone != "" {
+			ret.Zones[card.Zone] += v
+		}
+	}
+	return ret, nil
+}
+
+func (r *Room) pots() map[int]int {
+	if len(r.pot) == 0 {
+		return nil
+	}
+	ret := make(map[int]int)
+	for card, cnt := range r.pot {
+		if d, ok := r.deck(card.DeckId); ok {
+			if vd, ok := d.Deck.(ValuedDeck); ok {
+				v, _ := vd.Value(card.Card)
+				ret[card.DeckId] += v * cnt
+			}
+		}
+	}
+	return ret
+}
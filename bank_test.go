@@ -0,0 +1,79 @@
+package sim_board
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type testChips []int
+
+func (t testChips) Value(card Card) (int, bool) {
+	v, err := strconv.Atoi(string(card))
+	return v, err == nil
+}
+
+func (t testChips) Denominations() []Card {
+	ret := make([]Card, 0, len(t))
+	for _, v := range t {
+		ret = append(ret, Card(strconv.Itoa(v)))
+	}
+	return ret
+}
+
+func (t testChips) Count(Card) int {
+	return 0
+}
+
+func (t testChips) Take(Card, int) []Card {
+	return nil
+}
+
+func TestMakeChange(t *testing.T) {
+	chips := testChips{500, 100, 20, 5, 1}
+	plenty := func(Card) int { return 100 }
+	tests := []struct {
+		name   string
+		amount int
+		avail  func(Card) int
+		allow  func(Card) bool
+		plan   map[Card]int
+		ok     bool
+	}{
+		{"zero", 0, plenty, nil, map[Card]int{}, true},
+		{"exact denomination", 100, plenty, nil, map[Card]int{"100": 1}, true},
+		{"greedy", 627, plenty, nil, map[Card]int{"500": 1, "100": 1, "20": 1, "5": 1, "1": 2}, true},
+		{"limited supply", 600, func(c Card) int {
+			if c == "500" {
+				return 0
+			}
+			return 100
+		}, nil, map[Card]int{"100": 6}, true},
+		{"excluded denomination", 500, plenty, func(c Card) bool { return c != "500" }, map[Card]int{"100": 5}, true},
+		{"insufficient", 7, func(c Card) int {
+			if c == "5" {
+				return 1
+			}
+			return 0
+		}, nil, map[Card]int{"5": 1}, false},
+		{"nothing allowed", 10, plenty, func(Card) bool { return false }, map[Card]int{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, ok := makeChange(chips, tt.amount, tt.avail, tt.allow)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !reflect.DeepEqual(plan, tt.plan) {
+				t.Errorf("plan = %v, want %v", plan, tt.plan)
+			}
+		})
+	}
+	t.Run("unsorted denominations", func(t *testing.T) {
+		plan, ok := makeChange(testChips{1, 20, 500, 5, 100}, 627, plenty, nil)
+		want := map[Card]int{"500": 1, "100": 1, "20": 1, "5": 1, "1": 2}
+		if !ok || !reflect.DeepEqual(plan, want) {
+			t.Errorf("plan = %v, %v, want %v", plan, ok, want)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package sim_board
+
+type BatchOp string
+
+const (
+	BatchMove    BatchOp = "move"
+	BatchRotate  BatchOp = "rotate"
+	BatchRaise   BatchOp = "raise"
+	BatchLower   BatchOp = "lower"
+	BatchCollect BatchOp = "collect"
+	BatchDiscard BatchOp = "discard"
+)
+
+type BatchArgs struct {
+	Cards    []CollectArgs `json:"cards"`
+	Op       BatchOp       `json:"op"`
+	DX       float32       `json:"dx"`
+	DY       float32       `json:"dy"`
+	Zone     string        `json:"zone"`
+	Rotation int           `json:"rotation"`
+}
+
+type BatchResult struct {
+	Cards     map[string]*PublicCard `json:"cards,omitempty"`
+	Collected []DeckCard             `json:"collected,omitempty"`
+}
+
+func (r *Room) handleBatch(player string, args BatchArgs) (any, error) {
+	if len(args.Cards) == 0 {
+		return nil, newError("未选择公共牌")
+	}
+	cards := make([]*PublicCard, 0, len(args.Cards))
+	for i, c := range args.Cards {
+		for _, prev := range args.Cards[:i] {
+			if prev.ID == c.ID {
+				return nil, newError("公共牌重复：%s", c.ID)
+			}
+		}
+		card, err := r.boardCard(player, c.ID, c.OpID)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	ret := &BatchResult{}
+	switch args.Op {
+	case BatchMove:
+		targets, err := r.batchTargets(cards, args)
+		if err != nil {
+			return nil, err
+		}
+		for i, card := range cards {
+			t := targets[i]
+			card.X, card.Y, card.Zone = t.X, t.Y, t.Zone
+			card.OpID++
+			card.PlID = r.placeCnter
+			card.Z = r.topZ()
+			r.placeCnter++
+		}
+	case BatchRotate:
+		for _, card := range cards {
+			card.Rotation = (args.Rotation%360 + 360) % 360
+			card.OpID++
+		}
+	case BatchRaise:
+		z := r.topZ()
+		for i, card := range cards {
+			card.Z = z + i
+			card.OpID++
+		}
+	case BatchLower:
+		z := r.bottomZ() - len(cards) + 1
+		for i, card := range cards {
+			card.Z = z + i
+			card.OpID++
+		}
+	case BatchCollect:
+		for i, card := range cards {
+			r.hole[player][card.Card]++
+			delete(r.board, args.Cards[i].ID)
+			ret.Collected = append(ret.Collected, card.Card)
+		}
+		r.touchHand(player)
+	case BatchDiscard:
+		for _, card := range cards {
+			if _, ok := r.deck(card.Card.DeckId); !ok {
+				return nil, newError("牌堆不存在")
+			}
+		}
+		for i, card := range cards {
+			r.returnCard(card.Card)
+			delete(r.board, args.Cards[i].ID)
+		}
+	default:
+		return nil, newError("未知的批量操作：%s", args.Op)
+	}
+	if _, ok := r.board[args.Cards[0].ID]; ok {
+		ret.Cards = make(map[string]*PublicCard, len(cards))
+		for i, card := range cards {
+			ret.Cards[args.Cards[i].ID] = r.viewCard(player, card)
+		}
+	}
+	r.broadcast()
+	return ret, nil
+}
+
+func (r *Room) batchTargets(cards []*PublicCard, args BatchArgs) ([]PublicCard, error) {
+	var zone *Zone
+	if args.Zone != "" {
+		var ok bool
+		if zone, ok = r.zone(args.Zone); !ok {
+			return nil, newError("区域不存在")
+		}
+	}
+	targets := make([]PublicCard, 0, len(cards))
+	need := make(map[*Zone]int)
+	for _, card := range cards {
+		t := PublicCard{X: min(max(card.X+args.DX, 0), 1), Y: min(max(card.Y+args.DY, 0), 1)}
+		z := zone
+		if z != nil {
+			t.X, t.Y = getRandomPos(z)
+		} else {
+			z = r.zoneAt(t.X, t.Y)
+		}
+		if z != nil {
+			t.Zone = z.ID
+			if z.ID != card.Zone {
+				need[z]++
+			}
+		}
+		targets = append(targets, t)
+	}
+	for z, n := range need {
+		if err := r.checkZone(z, n); err != nil {
+			return nil, err
+		}
+	}
+	return targets, nil
+}
+
+type SweepArgs struct {
+	Deck int    `json:"deck"`
+	Zone string `json:"zone"`
+}
+
+func (r *Room) handleSweep(player string, args SweepArgs) (any, error) {
+	if _, ok := r.deck(args.Deck); !ok {
+		return nil, newError("牌堆不存在")
+	}
+	ret := &BatchResult{Collected: []DeckCard{}}
+	for id, card := range r.board {
+		if card.Card.DeckId != args.Deck || (args.Zone != "" && card.Zone != args.Zone) || r.checkLock(player, card) != nil {
+			continue
+		}
+		if z, ok := r.zone(card.Zone); ok && !z.visibleTo(player) {
+			continue
+		}
+		r.hole[player][card.Card]++
+		delete(r.board, id)
+		ret.Collected = append(ret.Collected, card.Card)
+	}
+	if len(ret.Collected) == 0 {
+		return ret, nil
+	}
+	r.touchHand(player)
+	r.broadcast()
+	return ret, nil
+}
@@ -0,0 +1,132 @@
+package sim_board
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testPlace(t *testing.T, r *Room, deck, num int, zone string) []string {
+	t.Helper()
+	res, err := testDo(r, r.Summary().Host, "draw", DrawArgs{Deck: deck, Num: num, Zone: zone})
+	if err != nil {
+		t.Fatalf("draw to board: %v", err)
+	}
+	var ret DrawResult
+	if err = json.Unmarshal(res.Result, &ret); err != nil {
+		t.Fatal(err)
+	}
+	return ret.IDs
+}
+
+func TestBatchAtomic(t *testing.T) {
+	tests := []struct {
+		name     string
+		cards    func(ids []string) []CollectArgs
+		op       BatchOp
+		zone     string
+		conflict bool
+	}{
+		{
+			name: "stale op id",
+			cards: func(ids []string) []CollectArgs {
+				return []CollectArgs{{ID: ids[0]}, {ID: ids[1], OpID: 1}, {ID: ids[2]}}
+			},
+			op:       BatchCollect,
+			conflict: true,
+		},
+		{
+			name: "duplicate card",
+			cards: func(ids []string) []CollectArgs {
+				return []CollectArgs{{ID: ids[0]}, {ID: ids[1]}, {ID: ids[0]}}
+			},
+			op: BatchDiscard,
+		},
+		{
+			name: "missing card",
+			cards: func(ids []string) []CollectArgs {
+				return []CollectArgs{{ID: ids[0]}, {ID: "missing"}}
+			},
+			op: BatchRotate,
+		},
+		{
+			name: "zone too small",
+			cards: func(ids []string) []CollectArgs {
+				return []CollectArgs{{ID: ids[0]}, {ID: ids[1]}, {ID: ids[2]}}
+			},
+			op:   BatchMove,
+			zone: "small",
+		},
+		{
+			name: "unknown op",
+			cards: func(ids []string) []CollectArgs {
+				return []CollectArgs{{ID: ids[0]}}
+			},
+			op: "flip",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRoom(t, "alice", "bob")
+			zone := &Zone{ID: "small", W: 0.2, H: 0.2, Capacity: 2}
+			if _, err := testDo(r, "alice", "set_zone", zone); err != nil {
+				t.Fatal(err)
+			}
+			deck := testAddDeck(t, r, 10)
+			ids := testPlace(t, r, deck, 3, "")
+			before := testState(t, r, "bob")
+			_, err := testDo(r, "bob", "batch", BatchArgs{Cards: tt.cards(ids), Op: tt.op, Zone: tt.zone, Rotation: 90})
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if IsConflict(err) != tt.conflict {
+				t.Errorf("conflict = %v, want %v: %v", IsConflict(err), tt.conflict, err)
+			}
+			after := testState(t, r, "bob")
+			if !reflect.DeepEqual(after.Board, before.Board) {
+				t.Errorf("board changed by a failed batch")
+			}
+			if len(after.Hole) != 0 || after.Decks[0].RestLen != before.Decks[0].RestLen {
+				t.Errorf("hand or deck changed by a failed batch")
+			}
+		})
+	}
+}
+
+func TestSweepSkipsHiddenZones(t *testing.T) {
+	r := newTestRoom(t, "alice", "bob")
+	zones := []*Zone{
+		{ID: "mine", W: 0.2, H: 0.2, Owner: "bob", Visibility: ZoneOwner},
+		{ID: "theirs", X: 0.8, W: 0.2, H: 0.2, Owner: "alice", Visibility: ZoneOwner},
+		{ID: "down", Y: 0.8, W: 0.2, H: 0.2, Visibility: ZoneFaceDown},
+	}
+	for _, z := range zones {
+		if _, err := testDo(r, "alice", "set_zone", z); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deck := testAddDeck(t, r, 10)
+	testPlace(t, r, deck, 2, "")
+	testPlace(t, r, deck, 1, "mine")
+	hidden := append(testPlace(t, r, deck, 1, "theirs"), testPlace(t, r, deck, 1, "down")...)
+	res, err := testDo(r, "bob", "sweep", SweepArgs{Deck: deck})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ret BatchResult
+	if err = json.Unmarshal(res.Result, &ret); err != nil {
+		t.Fatal(err)
+	}
+	if len(ret.Collected) != 3 {
+		t.Errorf("swept %d cards, want 3", len(ret.Collected))
+	}
+	board := testState(t, r, "bob").Board
+	if len(board) != len(hidden) {
+		t.Fatalf("%d cards left on the board, want %d", len(board), len(hidden))
+	}
+	for _, id := range hidden {
+		if _, ok := board[id]; !ok {
+			t.Errorf("hidden card %s was swept", id)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package sim_board
+
+func (r *Room) topZ() int {
+	z := 0
+	for _, card := range r.board {
+		z = max(z, card.Z+1)
+	}
+	return z
+}
+
+func (r *Room) bottomZ() int {
+	z := 0
+	for _, card := range r.board {
+		z = min(z, card.Z-1)
+	}
+	return z
+}
+
+func (r *Room) checkLock(player string, card *PublicCard) error {
+	if card.Owner != "" && card.Owner != player && player != r.host {
+		return newError("该牌已被%s锁定", card.Owner)
+	}
+	return nil
+}
+
+func (r *Room) boardCard(player, id string, opID uint) (*PublicCard, error) {
+	card, ok := r.board[id]
+	if !ok {
+		return nil, newError("公共牌不存在")
+	}
+	if card.OpID != opID {
+		return nil, newConflict("操作超时, src=%d, dst=%d", opID, card.OpID)
+	}
+	if err := r.checkLock(player, card); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+type RotateArgs struct {
+	ID       string `json:"id"`
+	OpID     uint   `json:"op_id"`
+	Rotation int    `json:"rotation"`
+}
+
+func (r *Room) handleRotate(player string, args RotateArgs) (any, error) {
+	card, err := r.boardCard(player, args.ID, args.OpID)
+	if err != nil {
+		return nil, err
+	}
+	card.Rotation = (args.Rotation%360 + 360) % 360
+	card.OpID++
+	r.broadcast()
+	return &BoardCardResult{ID: args.ID, Card: r.viewCard(player, card)}, nil
+}
+
+func (r *Room) handleRaise(player string, args CollectArgs) (any, error) {
+	card, err := r.boardCard(player, args.ID, args.OpID)
+	if err != nil {
+		return nil, err
+	}
+	card.Z = r.topZ()
+	card.OpID++
+	r.broadcast()
+	return &BoardCardResult{ID: args.ID, Card: r.viewCard(player, card)}, nil
+}
+
+func (r *Room) handleLower(player string, args CollectArgs) (any, error) {
+	card, err := r.boardCard(player, args.ID, args.OpID)
+	if err != nil {
+		return nil, err
+	}
+	card.Z = r.bottomZ()
+	card.OpID++
+	r.broadcast()
+	return &BoardCardResult{ID: args.ID, Card: r.viewCard(player, card)}, nil
+}
+
+type LockArgs struct {
+	ID    string `json:"id"`
+	OpID  uint   `json:"op_id"`
+	Owner string `json:"owner"`
+}
+
+func (r *Room) handleLock(player string, args LockArgs) (any, error) {
+	card, err := r.boardCard(player, args.ID, args.OpID)
+	if err != nil {
+		return nil, err
+	}
+	if args.Owner == "" {
+		args.Owner = player
+	}
+	if args.Owner != player {
+		if err = r.requireHost(player); err != nil {
+			return nil, err
+		}
+		if !SliceContains(r.players, args.Owner) {
+			return nil, newError("目标不存在")
+		}
+	}
+	card.Owner = args.Owner
+	card.OpID++
+	r.broadcast()
+	return &BoardCardResult{ID: args.ID, Card: r.viewCard(player, card)}, nil
+}
+
+func (r *Room) handleUnlock(player string, args CollectArgs) (any, error) {
+	card, err := r.boardCard(player, args.ID, args.OpID)
+	if err != nil {
+		return nil, err
+	}
+	card.Owner = ""
+	card.OpID++
+	r.broadcast()
+	return &BoardCardResult{ID: args.ID, Card: r.viewCard(player, card)}, nil
+}
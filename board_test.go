@@ -0,0 +1,70 @@
+package sim_board
+
+import (
+	"testing"
+)
+
+func TestLockedCards(t *testing.T) {
+	tests := []struct {
+		name    string
+		player  string
+		command string
+		args    func(id string, deck int) any
+		wantErr bool
+		kept    bool
+	}{
+		{"other moves", "carol", "move", func(id string, _ int) any { return MoveArgs{ID: id, OpID: 1, X: 0.1, Y: 0.1} }, true, true},
+		{"other collects", "carol", "collect", func(id string, _ int) any { return CollectArgs{ID: id, OpID: 1} }, true, true},
+		{"other discards", "carol", "discard_board", func(id string, _ int) any { return CollectArgs{ID: id, OpID: 1} }, true, true},
+		{"other rotates", "carol", "rotate", func(id string, _ int) any { return RotateArgs{ID: id, OpID: 1, Rotation: 90} }, true, true},
+		{"other unlocks", "carol", "unlock", func(id string, _ int) any { return CollectArgs{ID: id, OpID: 1} }, true, true},
+		{"other relocks", "carol", "lock", func(id string, _ int) any { return LockArgs{ID: id, OpID: 1} }, true, true},
+		{"other batch collects", "carol", "batch", func(id string, _ int) any {
+			return BatchArgs{Cards: []CollectArgs{{ID: id, OpID: 1}}, Op: BatchCollect}
+		}, true, true},
+		{"other sweeps", "carol", "sweep", func(_ string, deck int) any { return SweepArgs{Deck: deck} }, false, true},
+		{"other collects all", "carol", "all_collect", func(string, int) any { return struct{}{} }, false, true},
+		{"owner locks to other", "bob", "lock", func(id string, _ int) any { return LockArgs{ID: id, OpID: 1, Owner: "carol"} }, true, true},
+		{"owner moves", "bob", "move", func(id string, _ int) any { return MoveArgs{ID: id, OpID: 1, X: 0.1, Y: 0.1} }, false, true},
+		{"owner collects", "bob", "collect", func(id string, _ int) any { return CollectArgs{ID: id, OpID: 1} }, false, false},
+		{"host collects", "alice", "collect", func(id string, _ int) any { return CollectArgs{ID: id, OpID: 1} }, false, false},
+		{"host unlocks", "alice", "unlock", func(id string, _ int) any { return CollectArgs{ID: id, OpID: 1} }, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRoom(t, "alice", "bob", "carol")
+			deck := testAddDeck(t, r, 10)
+			id := testPlace(t, r, deck, 1, "")[0]
+			if _, err := testDo(r, "bob", "lock", LockArgs{ID: id}); err != nil {
+				t.Fatal(err)
+			}
+			before := *testState(t, r, "alice").Board[id]
+			_, err := testDo(r, tt.player, tt.command, tt.args(id, deck))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			card, ok := testState(t, r, "alice").Board[id]
+			if ok != tt.kept {
+				t.Fatalf("card on board = %v, want %v", ok, tt.kept)
+			}
+			if tt.wantErr && *card != before {
+				t.Errorf("locked card changed: %+v, was %+v", *card, before)
+			}
+		})
+	}
+}
+
+func TestLeaveUnlocks(t *testing.T) {
+	r := newTestRoom(t, "alice", "bob")
+	deck := testAddDeck(t, r, 10)
+	id := testPlace(t, r, deck, 1, "")[0]
+	if _, err := testDo(r, "bob", "lock", LockArgs{ID: id}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testDo(r, "bob", "leave", LeaveArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	if owner := testState(t, r, "alice").Board[id].Owner; owner != "" {
+		t.Errorf("owner = %q after leaving, want none", owner)
+	}
+}
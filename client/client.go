@@ -0,0 +1,460 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/KirCute/sim-board"
+)
+
+var ErrClosed = errors.New("client closed")
+
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type ConflictError struct {
+	Message string
+	State   *sim_board.BroadcastResponse
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+type Options struct {
+	Lang     string
+	Password string
+	Invite   string
+	Session  string
+	Create   bool
+	Meta     sim_board.RoomMeta
+}
+
+type transport interface {
+	call(ctx context.Context, cmd string, args any, expect *sim_board.Preconditions) (json.RawMessage, error)
+	close() error
+}
+
+type Client struct {
+	Room    string
+	Player  string
+	Lang    string
+	Session string
+
+	t      transport
+	events chan *Event
+	done   chan struct{}
+	err    error
+
+	lock        sync.RWMutex
+	state       *sim_board.BroadcastResponse
+	onBroadcast []func(*sim_board.BroadcastResponse)
+	onWelcome   []func(*sim_board.WelcomeResponse)
+	onEvent     []func(*Event)
+}
+
+func newClient(room, player string) *Client {
+	c := &Client{
+		Room:   room,
+		Player: player,
+		events: make(chan *Event, 256),
+		done:   make(chan struct{}),
+	}
+	go c.dispatchLoop()
+	return c
+}
+
+func (c *Client) OnBroadcast(f func(*sim_board.BroadcastResponse)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onBroadcast = append(c.onBroadcast, f)
+}
+
+func (c *Client) OnWelcome(f func(*sim_board.WelcomeResponse)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onWelcome = append(c.onWelcome, f)
+}
+
+func (c *Client) OnEvent(f func(*Event)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onEvent = append(c.onEvent, f)
+}
+
+func (c *Client) State() *sim_board.BroadcastResponse {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.state
+}
+
+func (c *Client) initState(state *sim_board.BroadcastResponse) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.state == nil {
+		c.state = state
+	}
+}
+
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+func (c *Client) Close() error {
+	return c.t.close()
+}
+
+func (c *Client) push(e *Event) {
+	select {
+	case c.events <- e:
+	case <-c.done:
+	}
+}
+
+func (c *Client) finish(err error) {
+	c.err = err
+	close(c.done)
+}
+
+func (c *Client) dispatchLoop() {
+	for {
+		select {
+		case e := <-c.events:
+			c.handleEvent(e)
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Client) handleEvent(e *Event) {
+	switch e.Type {
+	case "broadcast":
+		if state, err := e.Broadcast(); err == nil {
+			c.lock.Lock()
+			c.state = state
+			handlers := c.onBroadcast
+			c.lock.Unlock()
+			for _, f := range handlers {
+				f(state)
+			}
+		}
+	case "welcome":
+		if welcome, err := e.Welcome(); err == nil {
+			c.lock.Lock()
+			c.state = welcome.Broadcast
+			handlers := c.onWelcome
+			c.lock.Unlock()
+			for _, f := range handlers {
+				f(welcome)
+			}
+		}
+	}
+	c.lock.RLock()
+	handlers := c.onEvent
+	c.lock.RUnlock()
+	for _, f := range handlers {
+		f(e)
+	}
+}
+
+func (c *Client) Call(ctx context.Context, cmd string, args any, result any) error {
+	return c.CallExpect(ctx, cmd, args, nil, result)
+}
+
+func (c *Client) CallExpect(ctx context.Context, cmd string, args any, expect *sim_board.Preconditions, result any) error {
+	data, err := c.t.call(ctx, cmd, args, expect)
+	if err != nil {
+		return err
+	}
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+func (c *Client) Draw(ctx context.Context, deck, num int, target string) (*sim_board.DrawResult, error) {
+	var ret sim_board.DrawResult
+	err := c.Call(ctx, "draw", &sim_board.DrawArgs{Deck: deck, Num: num, Target: target}, &ret)
+	return &ret, err
+}
+
+func (c *Client) DrawToZone(ctx context.Context, deck, num int, zone string) (*sim_board.DrawResult, error) {
+	var ret sim_board.DrawResult
+	err := c.Call(ctx, "draw", &sim_board.DrawArgs{Deck: deck, Num: num, Zone: zone}, &ret)
+	return &ret, err
+}
+
+func (c *Client) Deal(ctx context.Context, args sim_board.DealArgs) (*sim_board.DealResult, error) {
+	var ret sim_board.DealResult
+	err := c.Call(ctx, "deal", &args, &ret)
+	return &ret, err
+}
+
+func (c *Client) Announce(ctx context.Context, card sim_board.DeckCard, x, y float32) (*sim_board.BoardCardResult, error) {
+	var ret sim_board.BoardCardResult
+	err := c.Call(ctx, "announce", &sim_board.AnnounceArgs{DeckCard: card, X: x, Y: y}, &ret)
+	return &ret, err
+}
+
+func (c *Client) Collect(ctx context.Context, id string, opID uint) (sim_board.DeckCard, error) {
+	var ret sim_board.DeckCard
+	err := c.Call(ctx, "collect", &sim_board.CollectArgs{ID: id, OpID: opID}, &ret)
+	return ret, err
+}
+
+func (c *Client) AllCollect(ctx context.Context) error {
+	return c.Call(ctx, "all_collect", nil, nil)
+}
+
+func (c *Client) DiscardBoard(ctx context.Context, id string, opID uint) error {
+	return c.Call(ctx, "discard_board", &sim_board.CollectArgs{ID: id, OpID: opID}, nil)
+}
+
+func (c *Client) DiscardHole(ctx context.Context, card sim_board.DeckCard) error {
+	return c.Call(ctx, "discard_hole", card, nil)
+}
+
+func (c *Client) AddDeck(ctx context.Context, name string, params any) (int, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return 0, err
+	}
+	var ret sim_board.AddDeckResult
+	err = c.Call(ctx, "add_deck", &sim_board.AddDeckArgs{Name: name, Params: data}, &ret)
+	return ret.Deck, err
+}
+
+func (c *Client) RemoveDeck(ctx context.Context, deck int) error {
+	return c.Call(ctx, "remove_deck", &sim_board.DeckArgs{Deck: deck}, nil)
+}
+
+func (c *Client) ReconfigureDeck(ctx context.Context, deck int, params any) (*sim_board.ReconfigureDeckResult, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	var ret sim_board.ReconfigureDeckResult
+	err = c.Call(ctx, "reconfigure_deck", &sim_board.ReconfigureDeckArgs{Deck: deck, Params: data}, &ret)
+	return &ret, err
+}
+
+func (c *Client) Move(ctx context.Context, id string, opID uint, x, y float32) (*sim_board.BoardCardResult, error) {
+	var ret sim_board.BoardCardResult
+	err := c.Call(ctx, "move", &sim_board.MoveArgs{ID: id, OpID: opID, X: x, Y: y}, &ret)
+	return &ret, err
+}
+
+func (c *Client) Batch(ctx context.Context, args sim_board.BatchArgs) (*sim_board.BatchResult, error) {
+	var ret sim_board.BatchResult
+	err := c.Call(ctx, "batch", &args, &ret)
+	return &ret, err
+}
+
+func (c *Client) Sweep(ctx context.Context, deck int, zone string) ([]sim_board.DeckCard, error) {
+	var ret sim_board.BatchResult
+	err := c.Call(ctx, "sweep", &sim_board.SweepArgs{Deck: deck, Zone: zone}, &ret)
+	return ret.Collected, err
+}
+
+func (c *Client) Lock(ctx context.Context, id string, opID uint, owner string) (*sim_board.BoardCardResult, error) {
+	var ret sim_board.BoardCardResult
+	err := c.Call(ctx, "lock", &sim_board.LockArgs{ID: id, OpID: opID, Owner: owner}, &ret)
+	return &ret, err
+}
+
+func (c *Client) Unlock(ctx context.Context, id string, opID uint) (*sim_board.BoardCardResult, error) {
+	var ret sim_board.BoardCardResult
+	err := c.Call(ctx, "unlock", &sim_board.CollectArgs{ID: id, OpID: opID}, &ret)
+	return &ret, err
+}
+
+func (c *Client) Withdraw(ctx context.Context, args sim_board.WithdrawArgs) (*sim_board.ChipsResult, error) {
+	var ret sim_board.ChipsResult
+	err := c.Call(ctx, "withdraw", &args, &ret)
+	return &ret, err
+}
+
+func (c *Client) Exchange(ctx context.Context, args sim_board.ExchangeArgs) (*sim_board.ChipsResult, error) {
+	var ret sim_board.ChipsResult
+	err := c.Call(ctx, "exchange", &args, &ret)
+	return &ret, err
+}
+
+func (c *Client) Bet(ctx context.Context, deck, amount int) (*sim_board.ChipsResult, error) {
+	var ret sim_board.ChipsResult
+	err := c.Call(ctx, "bet", &sim_board.BetArgs{Deck: deck, Amount: amount}, &ret)
+	return &ret, err
+}
+
+func (c *Client) Award(ctx context.Context, deck int, players ...string) (*sim_board.AwardResult, error) {
+	var ret sim_board.AwardResult
+	err := c.Call(ctx, "award", &sim_board.AwardArgs{Deck: deck, Players: players}, &ret)
+	return &ret, err
+}
+
+func (c *Client) Totals(ctx context.Context, deck int) (*sim_board.TotalsResult, error) {
+	var ret sim_board.TotalsResult
+	err := c.Call(ctx, "totals", &sim_board.DeckArgs{Deck: deck}, &ret)
+	return &ret, err
+}
+
+func (c *Client) Roll(ctx context.Context, notation string) (*sim_board.RollRecord, error) {
+	var ret sim_board.RollRecord
+	err := c.Call(ctx, "roll", &sim_board.RollArgs{Notation: notation}, &ret)
+	return &ret, err
+}
+
+func (c *Client) Reroll(ctx context.Context, id string, opID uint) (*sim_board.RollRecord, error) {
+	var ret sim_board.RollRecord
+	err := c.Call(ctx, "reroll", &sim_board.CollectArgs{ID: id, OpID: opID}, &ret)
+	return &ret, err
+}
+
+func (c *Client) Rotate(ctx context.Context, id string, opID uint, rotation int) (*sim_board.BoardCardResult, error) {
+	var ret sim_board.BoardCardResult
+	err := c.Call(ctx, "rotate", &sim_board.RotateArgs{ID: id, OpID: opID, Rotation: rotation}, &ret)
+	return &ret, err
+}
+
+func (c *Client) Raise(ctx context.Context, id string, opID uint) (*sim_board.BoardCardResult, error) {
+	var ret sim_board.BoardCardResult
+	err := c.Call(ctx, "raise", &sim_board.CollectArgs{ID: id, OpID: opID}, &ret)
+	return &ret, err
+}
+
+func (c *Client) Lower(ctx context.Context, id string, opID uint) (*sim_board.BoardCardResult, error) {
+	var ret sim_board.BoardCardResult
+	err := c.Call(ctx, "lower", &sim_board.CollectArgs{ID: id, OpID: opID}, &ret)
+	return &ret, err
+}
+
+func (c *Client) MoveToZone(ctx context.Context, id string, opID uint, zone string) (*sim_board.BoardCardResult, error) {
+	var ret sim_board.BoardCardResult
+	err := c.Call(ctx, "move", &sim_board.MoveArgs{ID: id, OpID: opID, Zone: zone}, &ret)
+	return &ret, err
+}
+
+func (c *Client) SetZone(ctx context.Context, zone sim_board.Zone) (*sim_board.Zone, error) {
+	var ret sim_board.Zone
+	err := c.Call(ctx, "set_zone", &zone, &ret)
+	return &ret, err
+}
+
+func (c *Client) RemoveZone(ctx context.Context, id string) error {
+	return c.Call(ctx, "remove_zone", &sim_board.ZoneArgs{ID: id}, nil)
+}
+
+func (c *Client) Reset(ctx context.Context) error {
+	return c.Call(ctx, "reset", nil, nil)
+}
+
+func (c *Client) Recall(ctx context.Context, args sim_board.RecallArgs) (*sim_board.RecallEvent, error) {
+	var ret sim_board.RecallEvent
+	err := c.Call(ctx, "recall", &args, &ret)
+	return &ret, err
+}
+
+func (c *Client) SetPassword(ctx context.Context, password string) error {
+	return c.Call(ctx, "set_password", &sim_board.SetPasswordArgs{Password: password}, nil)
+}
+
+func (c *Client) CreateInvite(ctx context.Context, ttl int64) (*sim_board.InviteResponse, error) {
+	var ret sim_board.InviteResponse
+	err := c.Call(ctx, "create_invite", &sim_board.CreateInviteArgs{TTL: ttl}, &ret)
+	return &ret, err
+}
+
+func (c *Client) RevokeInvites(ctx context.Context) error {
+	return c.Call(ctx, "revoke_invites", nil, nil)
+}
+
+func (c *Client) Leave(ctx context.Context, args sim_board.LeaveArgs) error {
+	return c.Call(ctx, "leave", &args, nil)
+}
+
+func (c *Client) KeepAlive(ctx context.Context) error {
+	return c.Call(ctx, "keep_alive", nil, nil)
+}
+
+func (c *Client) SetTTL(ctx context.Context, ttl int64) error {
+	return c.Call(ctx, "set_ttl", &sim_board.SetTTLArgs{TTL: ttl}, nil)
+}
+
+func (c *Client) CloseRoom(ctx context.Context) error {
+	return c.Call(ctx, "close_room", nil, nil)
+}
+
+func (c *Client) Kick(ctx context.Context, args sim_board.KickArgs) error {
+	return c.Call(ctx, "kick", &args, nil)
+}
+
+func (c *Client) Ban(ctx context.Context, args sim_board.KickArgs) error {
+	return c.Call(ctx, "ban", &args, nil)
+}
+
+func (c *Client) Unban(ctx context.Context, player string) error {
+	return c.Call(ctx, "unban", &sim_board.KickArgs{Player: player}, nil)
+}
+
+func (c *Client) SetScript(ctx context.Context, source string) error {
+	return c.Call(ctx, "set_script", &sim_board.SetScriptArgs{Source: source}, nil)
+}
+
+func (c *Client) GetScript(ctx context.Context) (string, error) {
+	var ret sim_board.SetScriptArgs
+	err := c.Call(ctx, "get_script", nil, &ret)
+	return ret.Source, err
+}
+
+func (c *Client) ApplyPreset(ctx context.Context, name string) (*sim_board.ApplyPresetResult, error) {
+	var ret sim_board.ApplyPresetResult
+	err := c.Call(ctx, "apply_preset", &sim_board.ApplyPresetArgs{Name: name}, &ret)
+	return &ret, err
+}
+
+func (c *Client) SavePreset(ctx context.Context, args sim_board.SavePresetArgs) (*sim_board.Preset, error) {
+	var ret sim_board.Preset
+	err := c.Call(ctx, "save_preset", &args, &ret)
+	return &ret, err
+}
+
+func (c *Client) ListPresets(ctx context.Context) ([]sim_board.Preset, error) {
+	var ret []sim_board.Preset
+	err := c.Call(ctx, "list_presets", nil, &ret)
+	return ret, err
+}
+
+func (c *Client) ListRooms(ctx context.Context) ([]sim_board.RoomSummary, error) {
+	var ret []sim_board.RoomSummary
+	err := c.Call(ctx, "list_rooms", nil, &ret)
+	return ret, err
+}
+
+type DownloadResult struct {
+	Deck string `json:"deck"`
+	Card string `json:"card"`
+	HTML string `json:"html"`
+}
+
+func (c *Client) Download(ctx context.Context, deck, card string) (*DownloadResult, error) {
+	var ret DownloadResult
+	err := c.Call(ctx, "download", &DownloadResult{Deck: deck, Card: card}, &ret)
+	return &ret, err
+}
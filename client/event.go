@@ -0,0 +1,63 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/KirCute/sim-board"
+)
+
+type Event struct {
+	Type  string          `json:"type"`
+	ReqID string          `json:"req_id,omitempty"`
+	Data  json.RawMessage `json:"data"`
+}
+
+func decode[T any](e *Event) (*T, error) {
+	var ret T
+	if err := json.Unmarshal(e.Data, &ret); err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+func (e *Event) Broadcast() (*sim_board.BroadcastResponse, error) {
+	return decode[sim_board.BroadcastResponse](e)
+}
+
+func (e *Event) Welcome() (*sim_board.WelcomeResponse, error) {
+	return decode[sim_board.WelcomeResponse](e)
+}
+
+func (e *Event) Presence() (*sim_board.PresenceEvent, error) {
+	return decode[sim_board.PresenceEvent](e)
+}
+
+func (e *Event) PlayerLeft() (*sim_board.PlayerLeftResponse, error) {
+	return decode[sim_board.PlayerLeftResponse](e)
+}
+
+func (e *Event) Expiry() (*sim_board.ExpiryResponse, error) {
+	return decode[sim_board.ExpiryResponse](e)
+}
+
+func (e *Event) RoomClosed() (*sim_board.RoomClosedResponse, error) {
+	return decode[sim_board.RoomClosedResponse](e)
+}
+
+func (e *Event) Invite() (*sim_board.InviteResponse, error) {
+	return decode[sim_board.InviteResponse](e)
+}
+
+func (e *Event) Recall() (*sim_board.RecallEvent, error) {
+	return decode[sim_board.RecallEvent](e)
+}
+
+func (e *Event) Roll() (*sim_board.RollRecord, error) {
+	return decode[sim_board.RollRecord](e)
+}
+
+func (e *Event) Message() string {
+	var s string
+	_ = json.Unmarshal(e.Data, &s)
+	return s
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/KirCute/sim-board"
+	"github.com/KirCute/sim-board/client"
+	"github.com/KirCute/sim-board/deck/poker"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	url := flag.String("url", "ws://localhost:6700/ws", "WebSocket endpoint of the server")
+	room := flag.String("room", "", "room to deal in")
+	name := flag.String("name", "dealer", "player name of the bot")
+	password := flag.String("password", "", "room password")
+	create := flag.Bool("create", false, "create the room instead of joining it")
+	num := flag.Int("num", 2, "cards dealt to each player per hand")
+	minPlayers := flag.Int("min-players", 2, "players required before a hand is dealt")
+	flag.Parse()
+	if *room == "" {
+		logrus.Fatal("-room is required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	c, err := client.Dial(ctx, *url, *room, *name, &client.Options{Password: *password, Create: *create})
+	if err != nil {
+		logrus.Fatalf("failed to join room: %v", err)
+	}
+	defer c.Close()
+
+	deck := -1
+	for _, d := range c.State().Decks {
+		if d.Type == poker.Name {
+			deck = d.ID
+		}
+	}
+	if deck < 0 {
+		deck, err = c.AddDeck(ctx, poker.Name, &poker.Params{
+			Count:           1,
+			CountSuit:       4,
+			CountRank:       13,
+			CountRedJoker:   1,
+			CountBlackJoker: 1,
+		})
+		if err != nil {
+			logrus.Fatalf("failed to add deck: %v", err)
+		}
+	}
+
+	states := make(chan *sim_board.BroadcastResponse, 1)
+	c.OnBroadcast(func(state *sim_board.BroadcastResponse) {
+		select {
+		case <-states:
+		default:
+		}
+		states <- state
+	})
+	logrus.Infof("dealing %d cards per player in room '%s', waiting for a new hand", *num, *room)
+	for {
+		select {
+		case state := <-states:
+			if newHand(state, deck, *name, *minPlayers) {
+				deal(ctx, c, state, deck, *num)
+			}
+		case <-c.Done():
+			if err = c.Err(); err != nil {
+				logrus.Fatalf("disconnected: %v", err)
+			}
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func findDeck(state *sim_board.BroadcastResponse, id int) *sim_board.MarshaledDeck {
+	for i := range state.Decks {
+		if state.Decks[i].ID == id {
+			return &state.Decks[i]
+		}
+	}
+	return nil
+}
+
+func newHand(state *sim_board.BroadcastResponse, deck int, dealer string, minPlayers int) bool {
+	d := findDeck(state, deck)
+	if len(state.Board) > 0 || d == nil || d.RestLen != d.MaxLen {
+		return false
+	}
+	players := 0
+	for _, p := range state.Players {
+		if p != dealer {
+			players++
+		}
+	}
+	return players >= minPlayers
+}
+
+func deal(ctx context.Context, c *client.Client, state *sim_board.BroadcastResponse, deck, num int) {
+	expect := &sim_board.Preconditions{Room: &state.Version}
+	for _, p := range state.Players {
+		if p == c.Player {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := c.CallExpect(ctx, "draw", &sim_board.DrawArgs{Deck: deck, Num: num, Target: p}, expect, nil)
+		cancel()
+		if err != nil {
+			logrus.Warnf("failed to deal to %s: %v", p, err)
+			return
+		}
+		expect = nil
+	}
+	logrus.Infof("dealt a new hand to %d players", len(state.Players)-1)
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/KirCute/sim-board"
+)
+
+type localTransport struct {
+	c    *Client
+	room *sim_board.Room
+	conn *sim_board.Conn
+}
+
+func Attach(ctx context.Context, room *sim_board.Room, player string, opts *Options) (*Client, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if err := room.Authorize(player, opts.Password, opts.Invite); err != nil {
+		return nil, &Error{Message: sim_board.LocalizeError(err, opts.Lang)}
+	}
+	lang, _ := sim_board.NormalizeLang(opts.Lang)
+	conn, err := room.Attach(ctx, player, lang)
+	if err != nil {
+		return nil, localError(err, lang, nil)
+	}
+	c := newClient(room.Name(), player)
+	c.Lang = lang
+	t := &localTransport{c: c, room: room, conn: conn}
+	c.t = t
+	go t.readLoop()
+	res, err := room.Request(ctx, &sim_board.ClientMessage{Command: "state", Player: player, Room: c.Room})
+	var state sim_board.BroadcastResponse
+	if err == nil {
+		err = json.Unmarshal(res.State, &state)
+	}
+	if err != nil {
+		_ = t.close()
+		return nil, localError(err, lang, nil)
+	}
+	c.initState(&state)
+	return c, nil
+}
+
+func (t *localTransport) readLoop() {
+	defer t.c.finish(nil)
+	for {
+		select {
+		case data := <-t.conn.Messages():
+			t.handle(data)
+		case <-t.conn.Done():
+			for {
+				select {
+				case data := <-t.conn.Messages():
+					t.handle(data)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (t *localTransport) handle(data []byte) {
+	var e Event
+	if json.Unmarshal(data, &e) == nil {
+		t.c.push(&e)
+	}
+}
+
+func localError(err error, lang string, res *sim_board.CommandResult) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	msg := sim_board.LocalizeError(err, lang)
+	if sim_board.IsConflict(err) && res != nil {
+		var state sim_board.BroadcastResponse
+		if json.Unmarshal(res.State, &state) == nil {
+			return &ConflictError{Message: msg, State: &state}
+		}
+	}
+	return &Error{Message: msg}
+}
+
+func (t *localTransport) call(ctx context.Context, cmd string, args any, expect *sim_board.Preconditions) (json.RawMessage, error) {
+	switch cmd {
+	case "list_rooms":
+		return json.Marshal(sim_board.ListRooms())
+	case "list_presets":
+		return json.Marshal(sim_board.ListPresets(t.c.Lang))
+	case "download":
+		req, _ := args.(*DownloadResult)
+		if req == nil {
+			return nil, &Error{Message: "invalid download request"}
+		}
+		html, ok := sim_board.GetCardHTML(req.Deck, req.Card)
+		if !ok {
+			return nil, &Error{Message: sim_board.Tr(t.c.Lang, "不存在的模型：%s@%s", req.Deck, req.Card)}
+		}
+		return json.Marshal(&DownloadResult{Deck: req.Deck, Card: req.Card, HTML: html})
+	}
+	msg := &sim_board.ClientMessage{Command: cmd, Player: t.c.Player, Room: t.c.Room, Expect: expect}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	msg.Data = data
+	res, err := t.room.Request(ctx, msg)
+	if err != nil {
+		return nil, localError(err, t.c.Lang, res)
+	}
+	return res.Result, nil
+}
+
+func (t *localTransport) close() error {
+	t.room.Detach(t.c.Player, t.conn)
+	return nil
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/KirCute/sim-board"
+	"github.com/gorilla/websocket"
+)
+
+type wsRequest struct {
+	ReqID   string                   `json:"req_id"`
+	Command string                   `json:"cmd"`
+	Player  string                   `json:"player"`
+	Room    string                   `json:"room"`
+	Data    any                      `json:"data"`
+	Expect  *sim_board.Preconditions `json:"expect,omitempty"`
+}
+
+type wsJoinArgs struct {
+	Lang     string `json:"lang,omitempty"`
+	Password string `json:"password,omitempty"`
+	Invite   string `json:"invite,omitempty"`
+	Session  string `json:"session,omitempty"`
+	sim_board.RoomMeta
+}
+
+type wsTransport struct {
+	c       *Client
+	ws      *websocket.Conn
+	writeMu sync.Mutex
+	nextID  atomic.Uint64
+	closing atomic.Bool
+	lock    sync.Mutex
+	pending map[string]chan *Event
+}
+
+func Dial(ctx context.Context, url, room, player string, opts *Options) (*Client, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	header := http.Header{}
+	if opts.Lang != "" {
+		header.Set("Accept-Language", opts.Lang)
+	}
+	ws, _, err := websocket.DefaultDialer.DialContext(ctx, url, header)
+	if err != nil {
+		return nil, err
+	}
+	c := newClient(room, player)
+	c.Lang = opts.Lang
+	t := &wsTransport{c: c, ws: ws, pending: make(map[string]chan *Event)}
+	c.t = t
+	go t.readLoop()
+	cmd := "join"
+	if opts.Create {
+		cmd = "create_room"
+	}
+	args := &wsJoinArgs{Lang: opts.Lang, Password: opts.Password, Invite: opts.Invite, Session: opts.Session, RoomMeta: opts.Meta}
+	data, err := t.call(ctx, cmd, args, nil)
+	var welcome sim_board.WelcomeResponse
+	if err == nil {
+		err = json.Unmarshal(data, &welcome)
+	}
+	if err != nil {
+		_ = t.close()
+		return nil, err
+	}
+	c.Session = opts.Session
+	if welcome.Session != "" {
+		c.Session = welcome.Session
+	}
+	c.initState(welcome.Broadcast)
+	return c, nil
+}
+
+func isResponse(e *Event) bool {
+	switch e.Type {
+	case "ack", "error", "conflict", "throttled", "welcome", "rooms", "presets", "download":
+		return true
+	}
+	return false
+}
+
+func (t *wsTransport) readLoop() {
+	var err error
+	defer func() {
+		t.lock.Lock()
+		for id, ch := range t.pending {
+			close(ch)
+			delete(t.pending, id)
+		}
+		t.lock.Unlock()
+		t.c.finish(err)
+	}()
+	for {
+		var data []byte
+		if _, data, err = t.ws.ReadMessage(); err != nil {
+			if t.closing.Load() || websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				err = nil
+			}
+			return
+		}
+		var e Event
+		if json.Unmarshal(data, &e) != nil {
+			continue
+		}
+		if e.ReqID != "" && isResponse(&e) {
+			t.lock.Lock()
+			ch, ok := t.pending[e.ReqID]
+			delete(t.pending, e.ReqID)
+			t.lock.Unlock()
+			if ok {
+				ch <- &e
+				if e.Type != "welcome" {
+					continue
+				}
+			}
+		}
+		t.c.push(&e)
+	}
+}
+
+func (t *wsTransport) call(ctx context.Context, cmd string, args any, expect *sim_board.Preconditions) (json.RawMessage, error) {
+	id := strconv.FormatUint(t.nextID.Add(1), 10)
+	ch := make(chan *Event, 1)
+	t.lock.Lock()
+	t.pending[id] = ch
+	t.lock.Unlock()
+	defer func() {
+		t.lock.Lock()
+		delete(t.pending, id)
+		t.lock.Unlock()
+	}()
+	req := &wsRequest{ReqID: id, Command: cmd, Player: t.c.Player, Room: t.c.Room, Data: args, Expect: expect}
+	t.writeMu.Lock()
+	err := t.ws.WriteJSON(req)
+	t.writeMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case e, ok := <-ch:
+		if !ok {
+			return nil, ErrClosed
+		}
+		return responseData(e)
+	case <-t.c.done:
+		return nil, ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func responseData(e *Event) (json.RawMessage, error) {
+	switch e.Type {
+	case "error", "throttled":
+		return nil, &Error{Message: e.Message()}
+	case "conflict":
+		var resp sim_board.ConflictResponse
+		if err := json.Unmarshal(e.Data, &resp); err != nil {
+			return nil, err
+		}
+		return nil, &ConflictError{Message: resp.Message, State: resp.State}
+	}
+	return e.Data, nil
+}
+
+func (t *wsTransport) close() error {
+	t.closing.Store(true)
+	t.writeMu.Lock()
+	_ = t.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	t.writeMu.Unlock()
+	return t.ws.Close()
+}
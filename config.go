@@ -0,0 +1,312 @@
+package sim_board
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/sirupsen/logrus"
+)
+
+const envPrefix = "SIM_BOARD_"
+
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(data []byte) error {
+	return d.Set(string(data))
+}
+
+type StringList []string
+
+func (l StringList) String() string {
+	return strings.Join(l, ",")
+}
+
+func (l *StringList) Set(s string) error {
+	*l = nil
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+type Config struct {
+	Listen            string     `yaml:"listen" toml:"listen"`
+	BasePath          string     `yaml:"base_path" toml:"base_path"`
+	TLSCert           string     `yaml:"tls_cert" toml:"tls_cert"`
+	TLSKey            string     `yaml:"tls_key" toml:"tls_key"`
+	RoomTTL           Duration   `yaml:"room_ttl" toml:"room_ttl"`
+	RoomExpiryWarning Duration   `yaml:"room_expiry_warning" toml:"room_expiry_warning"`
+	ArchiveDir        string     `yaml:"archive_dir" toml:"archive_dir"`
+	MaxRooms          int        `yaml:"max_rooms" toml:"max_rooms"`
+	MaxPlayers        int        `yaml:"max_players" toml:"max_players"`
+	CmdBufferSize     int        `yaml:"cmd_buffer_size" toml:"cmd_buffer_size"`
+	JoinBufferSize    int        `yaml:"join_buffer_size" toml:"join_buffer_size"`
+	MaxMessageSize    int64      `yaml:"max_message_size" toml:"max_message_size"`
+	SendQueueSize     int        `yaml:"send_queue_size" toml:"send_queue_size"`
+	WriteTimeout      Duration   `yaml:"write_timeout" toml:"write_timeout"`
+	PingInterval      Duration   `yaml:"ping_interval" toml:"ping_interval"`
+	PongTimeout       Duration   `yaml:"pong_timeout" toml:"pong_timeout"`
+	MaxConnsPerIP     int        `yaml:"max_conns_per_ip" toml:"max_conns_per_ip"`
+	MaxRoomsPerIP     int        `yaml:"max_rooms_per_ip" toml:"max_rooms_per_ip"`
+	RateLimit         float64    `yaml:"rate_limit" toml:"rate_limit"`
+	RateBurst         int        `yaml:"rate_burst" toml:"rate_burst"`
+	LogLevel          string     `yaml:"log_level" toml:"log_level"`
+	LogFormat         string     `yaml:"log_format" toml:"log_format"`
+	AllowedOrigins    StringList `yaml:"allowed_origins" toml:"allowed_origins"`
+	TrustedProxies    StringList `yaml:"trusted_proxies" toml:"trusted_proxies"`
+	ScriptTimeout     Duration   `yaml:"script_timeout" toml:"script_timeout"`
+	MaxScriptSize     int        `yaml:"max_script_size" toml:"max_script_size"`
+	ScriptMemory      int64      `yaml:"script_memory" toml:"script_memory"`
+	PresetFile        string     `yaml:"preset_file" toml:"preset_file"`
+	MaxPresets        int        `yaml:"max_presets" toml:"max_presets"`
+	MaxDeal           int        `yaml:"max_deal" toml:"max_deal"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Listen:            ":6700",
+		BasePath:          "",
+		RoomTTL:           Duration(30 * time.Minute),
+		RoomExpiryWarning: Duration(5 * time.Minute),
+		MaxRooms:          0,
+		MaxPlayers:        0,
+		CmdBufferSize:     64,
+		JoinBufferSize:    8,
+		MaxMessageSize:    64 * 1024,
+		SendQueueSize:     256,
+		WriteTimeout:      Duration(10 * time.Second),
+		PingInterval:      Duration(30 * time.Second),
+		PongTimeout:       Duration(60 * time.Second),
+		MaxConnsPerIP:     16,
+		MaxRoomsPerIP:     8,
+		RateLimit:         20,
+		RateBurst:         40,
+		LogLevel:          "info",
+		LogFormat:         "text",
+		ScriptTimeout:     Duration(100 * time.Millisecond),
+		MaxScriptSize:     64 * 1024,
+		ScriptMemory:      64 * 1024 * 1024,
+		MaxPresets:        256,
+		MaxDeal:           100,
+	}
+}
+
+var config = DefaultConfig()
+
+func (c *Config) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("sim_board", flag.ContinueOnError)
+	fs.String("config", "", "path to a YAML or TOML config file")
+	fs.StringVar(&c.Listen, "listen", c.Listen, "listen address")
+	fs.StringVar(&c.BasePath, "base-path", c.BasePath, "path prefix of all routes, e.g. /sim-board")
+	fs.StringVar(&c.TLSCert, "tls-cert", c.TLSCert, "TLS certificate file")
+	fs.StringVar(&c.TLSKey, "tls-key", c.TLSKey, "TLS private key file")
+	fs.Var(&c.RoomTTL, "room-ttl", "idle time before a room expires")
+	fs.Var(&c.RoomExpiryWarning, "room-expiry-warning", "how long before expiry the players are warned")
+	fs.StringVar(&c.ArchiveDir, "archive-dir", c.ArchiveDir, "directory to persist the final state of closed rooms, empty to disable")
+	fs.IntVar(&c.MaxRooms, "max-rooms", c.MaxRooms, "maximum number of rooms, 0 for unlimited")
+	fs.IntVar(&c.MaxPlayers, "max-players", c.MaxPlayers, "maximum number of players per room, 0 for unlimited")
+	fs.IntVar(&c.CmdBufferSize, "cmd-buffer-size", c.CmdBufferSize, "command queue size of each room")
+	fs.IntVar(&c.JoinBufferSize, "join-buffer-size", c.JoinBufferSize, "join/quit queue size of each room")
+	fs.Int64Var(&c.MaxMessageSize, "max-message-size", c.MaxMessageSize, "maximum size in bytes of a WebSocket message")
+	fs.IntVar(&c.SendQueueSize, "send-queue-size", c.SendQueueSize, "outbound messages buffered per connection before it is dropped as a slow consumer")
+	fs.Var(&c.WriteTimeout, "write-timeout", "deadline of a single WebSocket write")
+	fs.Var(&c.PingInterval, "ping-interval", "interval between WebSocket pings")
+	fs.Var(&c.PongTimeout, "pong-timeout", "connection is closed if nothing is received within this duration")
+	fs.IntVar(&c.MaxConnsPerIP, "max-conns-per-ip", c.MaxConnsPerIP, "maximum number of WebSocket connections per IP, 0 for unlimited")
+	fs.IntVar(&c.MaxRoomsPerIP, "max-rooms-per-ip", c.MaxRoomsPerIP, "maximum number of rooms created per IP, 0 for unlimited")
+	fs.Float64Var(&c.RateLimit, "rate-limit", c.RateLimit, "commands per second allowed on each connection, 0 for unlimited")
+	fs.IntVar(&c.RateBurst, "rate-burst", c.RateBurst, "command burst allowed on each connection")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "log level: trace, debug, info, warn, error")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "log format: text or json")
+	fs.Var(&c.AllowedOrigins, "allowed-origins", "comma-separated origins allowed to open WebSocket connections, * for any")
+	fs.Var(&c.TrustedProxies, "trusted-proxies", "comma-separated proxy IPs or CIDRs whose X-Forwarded-For is trusted, empty to trust none")
+	fs.Var(&c.ScriptTimeout, "script-timeout", "execution time limit of a room script per event")
+	fs.IntVar(&c.MaxScriptSize, "max-script-size", c.MaxScriptSize, "maximum size in bytes of a room script")
+	fs.Int64Var(&c.ScriptMemory, "script-memory", c.ScriptMemory, "bytes a room script may allocate per event before it is aborted")
+	fs.StringVar(&c.PresetFile, "preset-file", c.PresetFile, "file to persist user-saved presets, empty to keep them in memory")
+	fs.IntVar(&c.MaxPresets, "max-presets", c.MaxPresets, "maximum number of user-saved presets, 0 for unlimited")
+	fs.IntVar(&c.MaxDeal, "max-deal", c.MaxDeal, "maximum number of cards drawn or dealt to each player at once from an endless deck")
+	return fs
+}
+
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+func LoadConfig(args []string) (*Config, error) {
+	c := DefaultConfig()
+	fs := c.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	explicit := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+	path, ok := explicit["config"]
+	if !ok {
+		path = os.Getenv(envName("config"))
+	}
+	*c = *DefaultConfig()
+	if path != "" {
+		if err := c.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if v, ok := os.LookupEnv(envName(f.Name)); ok && err == nil && f.Name != "config" {
+			if e := fs.Set(f.Name, v); e != nil {
+				err = fmt.Errorf("invalid %s: %w", envName(f.Name), e)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for name, v := range explicit {
+		if err = fs.Set(name, v); err != nil {
+			return nil, fmt.Errorf("invalid -%s: %w", name, err)
+		}
+	}
+	if err = c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".toml":
+		err = toml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("unsupported config file format: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) Validate() error {
+	if c.Listen == "" {
+		return errors.New("listen address must not be empty")
+	}
+	c.BasePath = strings.TrimRight(c.BasePath, "/")
+	if c.BasePath != "" && !strings.HasPrefix(c.BasePath, "/") {
+		return fmt.Errorf("base path must start with /: %s", c.BasePath)
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return errors.New("tls cert and tls key must be set together")
+	}
+	for _, f := range []string{c.TLSCert, c.TLSKey} {
+		if f == "" {
+			continue
+		}
+		if _, err := os.Stat(f); err != nil {
+			return fmt.Errorf("tls file not accessible: %w", err)
+		}
+	}
+	if c.RoomTTL <= 0 {
+		return errors.New("room ttl must be positive")
+	}
+	if c.RoomExpiryWarning < 0 || c.RoomExpiryWarning >= c.RoomTTL {
+		return errors.New("room expiry warning must be non-negative and less than room ttl")
+	}
+	if c.MaxRooms < 0 || c.MaxPlayers < 0 {
+		return errors.New("limits must not be negative")
+	}
+	if c.CmdBufferSize <= 0 || c.JoinBufferSize <= 0 {
+		return errors.New("buffer sizes must be positive")
+	}
+	if c.MaxMessageSize <= 0 {
+		return errors.New("max message size must be positive")
+	}
+	if c.SendQueueSize <= 0 || c.WriteTimeout <= 0 {
+		return errors.New("send queue size and write timeout must be positive")
+	}
+	if c.PingInterval <= 0 || c.PongTimeout <= c.PingInterval {
+		return errors.New("ping interval must be positive and less than pong timeout")
+	}
+	if c.MaxConnsPerIP < 0 || c.MaxRoomsPerIP < 0 {
+		return errors.New("per-IP quotas must not be negative")
+	}
+	if c.RateLimit < 0 || (c.RateLimit > 0 && c.RateBurst < 1) {
+		return errors.New("rate limit must not be negative and rate burst must be at least 1")
+	}
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		return err
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("unknown log format: %s", c.LogFormat)
+	}
+	if c.ScriptTimeout <= 0 || c.MaxScriptSize <= 0 || c.ScriptMemory <= 0 {
+		return errors.New("script timeout, max script size and script memory must be positive")
+	}
+	if c.MaxPresets < 0 {
+		return errors.New("max presets must not be negative")
+	}
+	if c.MaxDeal <= 0 {
+		return errors.New("max deal must be positive")
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid allowed origin: %s", origin)
+		}
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid trusted proxy: %s", proxy)
+		}
+	}
+	return nil
+}
+
+func (c *Config) apply() {
+	level, _ := logrus.ParseLevel(c.LogLevel)
+	logrus.SetLevel(level)
+	if c.LogFormat == "json" {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	}
+	config = c
+}
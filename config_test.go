@@ -0,0 +1,125 @@
+package sim_board
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		ext       string
+		configEnv bool
+		env       map[string]string
+		args      []string
+		listen    string
+		roomTTL   time.Duration
+		maxRooms  int
+		proxies   string
+		wantErr   bool
+	}{
+		{name: "defaults", listen: ":6700", roomTTL: 30 * time.Minute},
+		{
+			name:     "yaml file",
+			file:     "listen: \":7000\"\nroom_ttl: 1h\nmax_rooms: 5\n",
+			ext:      ".yaml",
+			listen:   ":7000",
+			roomTTL:  time.Hour,
+			maxRooms: 5,
+		},
+		{
+			name:     "toml file",
+			file:     "listen = \":7000\"\nroom_ttl = \"1h\"\nmax_rooms = 5\n",
+			ext:      ".toml",
+			listen:   ":7000",
+			roomTTL:  time.Hour,
+			maxRooms: 5,
+		},
+		{
+			name:     "env over file",
+			file:     "listen: \":7000\"\nmax_rooms: 5\n",
+			ext:      ".yml",
+			env:      map[string]string{"SIM_BOARD_LISTEN": ":8000"},
+			listen:   ":8000",
+			roomTTL:  30 * time.Minute,
+			maxRooms: 5,
+		},
+		{
+			name:     "flag over env and file",
+			file:     "listen: \":7000\"\nroom_ttl: 1h\n",
+			ext:      ".yaml",
+			env:      map[string]string{"SIM_BOARD_LISTEN": ":8000", "SIM_BOARD_ROOM_TTL": "2h"},
+			args:     []string{"-listen", ":9000"},
+			listen:   ":9000",
+			roomTTL:  2 * time.Hour,
+			maxRooms: 0,
+		},
+		{
+			name:      "config path from env",
+			file:      "max_rooms: 3\n",
+			ext:       ".yaml",
+			configEnv: true,
+			listen:    ":6700",
+			roomTTL:   30 * time.Minute,
+			maxRooms:  3,
+		},
+		{
+			name:    "trusted proxies",
+			file:    "trusted_proxies: [\"10.0.0.1\"]\n",
+			ext:     ".yaml",
+			env:     map[string]string{"SIM_BOARD_TRUSTED_PROXIES": "10.0.0.0/8, 192.168.1.1"},
+			listen:  ":6700",
+			roomTTL: 30 * time.Minute,
+			proxies: "10.0.0.0/8,192.168.1.1",
+		},
+		{name: "invalid trusted proxy", args: []string{"-trusted-proxies", "proxy.local"}, wantErr: true},
+		{name: "invalid env", env: map[string]string{"SIM_BOARD_MAX_ROOMS": "many"}, wantErr: true},
+		{name: "invalid flag", args: []string{"-room-ttl", "soon"}, wantErr: true},
+		{name: "unsupported file", file: "listen=:7000", ext: ".ini", wantErr: true},
+		{name: "invalid value", args: []string{"-listen", ""}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), "config"+tt.ext)
+				if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if tt.configEnv {
+					t.Setenv("SIM_BOARD_CONFIG", path)
+				} else {
+					args = append([]string{"-config", path}, args...)
+				}
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			c, err := LoadConfig(args)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.Listen != tt.listen {
+				t.Errorf("listen = %q, want %q", c.Listen, tt.listen)
+			}
+			if time.Duration(c.RoomTTL) != tt.roomTTL {
+				t.Errorf("room ttl = %v, want %v", time.Duration(c.RoomTTL), tt.roomTTL)
+			}
+			if c.MaxRooms != tt.maxRooms {
+				t.Errorf("max rooms = %d, want %d", c.MaxRooms, tt.maxRooms)
+			}
+			if c.TrustedProxies.String() != tt.proxies {
+				t.Errorf("trusted proxies = %q, want %q", c.TrustedProxies, tt.proxies)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package sim_board
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+type Conn struct {
+	ws        *websocket.Conn
+	ip        string
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+	rooms     atomic.Int32
+}
+
+func newConn(ws *websocket.Conn, ip string) *Conn {
+	c := &Conn{
+		ws:   ws,
+		ip:   ip,
+		send: make(chan []byte, config.SendQueueSize),
+		done: make(chan struct{}),
+	}
+	pongTimeout := time.Duration(config.PongTimeout)
+	_ = ws.SetReadDeadline(time.Now().Add(pongTimeout))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+	return c
+}
+
+func newLocalConn(name string) *Conn {
+	return &Conn{
+		ip:   name,
+		send: make(chan []byte, config.SendQueueSize),
+		done: make(chan struct{}),
+	}
+}
+
+func (c *Conn) Messages() <-chan []byte {
+	return c.send
+}
+
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Conn) String() string {
+	return c.ip
+}
+
+func (c *Conn) Closed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Conn) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+func (c *Conn) attach() {
+	c.rooms.Add(1)
+}
+
+func (c *Conn) detach(close bool) {
+	if c.rooms.Add(-1) <= 0 && close {
+		c.Close()
+	}
+}
+
+func (c *Conn) Send(data []byte) bool {
+	if c.Closed() {
+		return false
+	}
+	select {
+	case c.send <- data:
+		return true
+	default:
+		logrus.Warnf("outbound queue of %s is full, dropping slow consumer", c.ip)
+		c.Close()
+		return false
+	}
+}
+
+func (c *Conn) SendJSON(msg *ServerMessage) bool {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		logrus.Errorf("write message to %s failed due to marshal error, err=%v, msg_type=%s", c.ip, err, msg.Type)
+		return false
+	}
+	return c.Send(data)
+}
+
+func (c *Conn) write(messageType int, data []byte) error {
+	_ = c.ws.SetWriteDeadline(time.Now().Add(time.Duration(config.WriteTimeout)))
+	return c.ws.WriteMessage(messageType, data)
+}
+
+func (c *Conn) writeLoop() {
+	ticker := time.NewTicker(time.Duration(config.PingInterval))
+	defer func() {
+		ticker.Stop()
+		c.Close()
+		if err := c.ws.Close(); err != nil {
+			logrus.Errorf("failed to close WebSocket connection from %s: %+v", c.ip, err)
+		} else {
+			logrus.Infof("WebSocket connection from %s closed", c.ip)
+		}
+	}()
+	for {
+		select {
+		case data := <-c.send:
+			if err := c.write(websocket.TextMessage, data); err != nil {
+				logrus.Errorf("write message to %s failed, err=%v, msg=%s", c.ip, err, string(data))
+				return
+			}
+		case <-ticker.C:
+			if err := c.write(websocket.PingMessage, nil); err != nil {
+				logrus.Errorf("ping %s failed: %+v", c.ip, err)
+				return
+			}
+		case <-c.done:
+			for {
+				select {
+				case data := <-c.send:
+					if c.write(websocket.TextMessage, data) != nil {
+						return
+					}
+				default:
+					_ = c.write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package sim_board
+
+type DealArgs struct {
+	Deck    int      `json:"deck"`
+	Num     int      `json:"num"`
+	Players []string `json:"players"`
+	All     bool     `json:"all"`
+}
+
+type DealResult struct {
+	Num   int            `json:"num"`
+	Dealt map[string]int `json:"dealt"`
+}
+
+func checkDealNum(d *roomDeck, num int) error {
+	if num <= 0 {
+		return newError("数量不足")
+	}
+	if d.RestLen() < 0 && num > config.MaxDeal {
+		return newError("单次最多%d张", config.MaxDeal)
+	}
+	return nil
+}
+
+func (r *Room) dealCards(d *roomDeck, num int, players []string) {
+	for i := 0; i < num; i++ {
+		for _, player := range players {
+			for _, card := range d.Draw(1) {
+				r.hole[player][DeckCard{DeckId: d.id, Card: card}]++
+			}
+		}
+	}
+	for _, player := range players {
+		r.touchHand(player)
+	}
+	d.version++
+}
+
+func (r *Room) handleDeal(player string, args DealArgs) (any, error) {
+	d, ok := r.deck(args.Deck)
+	if !ok {
+		return nil, newError("牌堆不存在")
+	}
+	players := r.players
+	if len(args.Players) > 0 {
+		players = make([]string, 0, len(args.Players))
+		for _, p := range args.Players {
+			if _, ok := r.hole[p]; !ok {
+				return nil, newError("目标不存在")
+			}
+			if !SliceContains(players, p) {
+				players = append(players, p)
+			}
+		}
+	}
+	if len(players) == 0 {
+		return nil, newError("目标不存在")
+	}
+	num := args.Num
+	if args.All {
+		if d.RestLen() < 0 {
+			return nil, newError("该牌堆无法全部发完")
+		}
+		num = d.RestLen() / len(players)
+	}
+	if err := checkDealNum(d, num); err != nil {
+		return nil, err
+	}
+	if d.RestLen() >= 0 && num > d.RestLen()/len(players) {
+		return nil, newError("数量不足")
+	}
+	r.dealCards(d, num, players)
+	r.broadcast()
+	ret := &DealResult{Num: num, Dealt: make(map[string]int, len(players))}
+	for _, p := range players {
+		ret.Dealt[p] = num
+	}
+	return ret, nil
+}
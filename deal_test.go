@@ -0,0 +1,121 @@
+package sim_board
+
+import (
+	"testing"
+)
+
+func TestDealAllOrNothing(t *testing.T) {
+	players := []string{"alice", "bob", "carol"}
+	tests := []struct {
+		name    string
+		size    int
+		command string
+		args    func(deck int) any
+		wantErr bool
+		hand    map[string]int
+	}{
+		{
+			name:    "deal within deck",
+			size:    10,
+			command: "deal",
+			args:    func(deck int) any { return DealArgs{Deck: deck, Num: 3} },
+			hand:    map[string]int{"alice": 3, "bob": 3, "carol": 3},
+		},
+		{
+			name:    "deal beyond deck",
+			size:    10,
+			command: "deal",
+			args:    func(deck int) any { return DealArgs{Deck: deck, Num: 4} },
+			wantErr: true,
+		},
+		{
+			name:    "deal to unknown player",
+			size:    10,
+			command: "deal",
+			args:    func(deck int) any { return DealArgs{Deck: deck, Num: 1, Players: []string{"alice", "mallory"}} },
+			wantErr: true,
+		},
+		{
+			name:    "deal all",
+			size:    10,
+			command: "deal",
+			args:    func(deck int) any { return DealArgs{Deck: deck, All: true} },
+			hand:    map[string]int{"alice": 3, "bob": 3, "carol": 3},
+		},
+		{
+			name:    "deal endless within limit",
+			size:    -1,
+			command: "deal",
+			args:    func(deck int) any { return DealArgs{Deck: deck, Num: config.MaxDeal} },
+			hand:    map[string]int{"alice": config.MaxDeal, "bob": config.MaxDeal, "carol": config.MaxDeal},
+		},
+		{
+			name:    "deal endless beyond limit",
+			size:    -1,
+			command: "deal",
+			args:    func(deck int) any { return DealArgs{Deck: deck, Num: config.MaxDeal + 1} },
+			wantErr: true,
+		},
+		{
+			name:    "deal overflowing count",
+			size:    10,
+			command: "deal",
+			args:    func(deck int) any { return DealArgs{Deck: deck, Num: 1 << 62} },
+			wantErr: true,
+		},
+		{
+			name:    "deal negative count",
+			size:    -1,
+			command: "deal",
+			args:    func(deck int) any { return DealArgs{Deck: deck, Num: -1} },
+			wantErr: true,
+		},
+		{
+			name:    "draw endless beyond limit",
+			size:    -1,
+			command: "draw",
+			args:    func(deck int) any { return DrawArgs{Deck: deck, Num: config.MaxDeal + 1, Target: "alice"} },
+			wantErr: true,
+		},
+		{
+			name:    "draw negative count",
+			size:    -1,
+			command: "draw",
+			args:    func(deck int) any { return DrawArgs{Deck: deck, Num: -1, Target: "alice"} },
+			wantErr: true,
+		},
+		{
+			name:    "draw into hand",
+			size:    -1,
+			command: "draw",
+			args:    func(deck int) any { return DrawArgs{Deck: deck, Num: 2, Target: "alice"} },
+			hand:    map[string]int{"alice": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRoom(t, players...)
+			deck := testAddDeck(t, r, tt.size)
+			_, err := testDo(r, "alice", tt.command, tt.args(deck))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			dealt := 0
+			for _, p := range players {
+				n := 0
+				for _, c := range testState(t, r, p).Hole {
+					n += c
+				}
+				if n != tt.hand[p] {
+					t.Errorf("%s holds %d cards, want %d", p, n, tt.hand[p])
+				}
+				dealt += n
+			}
+			if tt.size >= 0 {
+				if rest := testState(t, r, "alice").Decks[0].RestLen; rest != tt.size-dealt {
+					t.Errorf("deck has %d cards left, want %d", rest, tt.size-dealt)
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package chip
 import (
 	"fmt"
 	"math/rand"
+	"strconv"
 
 	"github.com/KirCute/sim-board"
 )
@@ -24,6 +25,10 @@ func (c *Chip) Name() string {
 	return c.CustomName
 }
 
+func (c *Chip) Rename(name string) {
+	c.CustomName = name
+}
+
 func (c *Chip) RestLen() int {
 	return len(c.Pool)
 }
@@ -49,6 +54,39 @@ func (c *Chip) Draw(count int) []sim_board.Card {
 	return ret
 }
 
+func (c *Chip) Value(card sim_board.Card) (int, bool) {
+	v, err := strconv.Atoi(string(card))
+	return v, err == nil && v > 0
+}
+
+func (c *Chip) Denominations() []sim_board.Card {
+	return []sim_board.Card{"10000", "2000", "500", "100", "20", "5", "1"}
+}
+
+func (c *Chip) Count(card sim_board.Card) int {
+	n := 0
+	for _, chip := range c.Pool {
+		if chip == card {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *Chip) Take(card sim_board.Card, n int) []sim_board.Card {
+	ret := make([]sim_board.Card, 0, n)
+	rest := c.Pool[:0]
+	for _, chip := range c.Pool {
+		if chip == card && len(ret) < n {
+			ret = append(ret, chip)
+			continue
+		}
+		rest = append(rest, chip)
+	}
+	c.Pool = rest
+	return ret
+}
+
 func GetHTML(card sim_board.Card) (string, bool) {
 	var color string
 	var size, fontSize int
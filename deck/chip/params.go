@@ -47,4 +47,15 @@ func Create(params *Params) *Chip {
 
 func init() {
 	sim_board.RegisterDeck(Name, reflect.ValueOf(Create), GetHTML)
+	sim_board.RegisterTranslations("en", map[string]string{
+		Name:         "Chips",
+		"自定义名称":      "Custom name",
+		"面值1数量":      "Chips of 1",
+		"面值5数量":      "Chips of 5",
+		"面值20数量":     "Chips of 20",
+		"面值100数量":    "Chips of 100",
+		"面值500数量":    "Chips of 500",
+		"面值2,000数量":  "Chips of 2,000",
+		"面值10,000数量": "Chips of 10,000",
+	})
 }
@@ -2,6 +2,7 @@ package dice
 
 import (
 	"fmt"
+	"html"
 	"math/rand"
 	"strconv"
 
@@ -9,6 +10,9 @@ import (
 )
 
 type Dice struct {
+	faces       []sim_board.Card
+	weights     []int
+	totalWeight int
 	*Params
 }
 
@@ -17,11 +21,28 @@ func (p *Dice) Type() string {
 }
 
 func (p *Dice) Name() string {
+	return p.LocalizedName(sim_board.DefaultLang)
+}
+
+func (p *Dice) Rename(name string) {
+	p.CustomName = name
+}
+
+func (p *Dice) LocalizedName(lang string) string {
 	if len(p.CustomName) == 0 {
-		if p.Face == 6 {
-			return Name
+		switch {
+		case p.Faces != "":
+		case p.Kind == KindFudge:
+			return sim_board.Tr(lang, "命运%s", sim_board.Translate(lang, Name))
+		case p.Kind == KindPercentile:
+			return sim_board.Tr(lang, "百分%s", sim_board.Translate(lang, Name))
+		case p.Kind == KindPercentileUnits:
+			return sim_board.Tr(lang, "百分%s（个位）", sim_board.Translate(lang, Name))
 		}
-		return fmt.Sprintf("%d面%s", p.Face, Name)
+		if len(p.faces) == 6 {
+			return sim_board.Translate(lang, Name)
+		}
+		return sim_board.Tr(lang, "%d面%s", len(p.faces), sim_board.Translate(lang, Name))
 	}
 	return p.CustomName
 }
@@ -40,12 +61,36 @@ func (p *Dice) Return(_ sim_board.Card) {
 func (p *Dice) Draw(count int) []sim_board.Card {
 	ret := make([]sim_board.Card, 0, count)
 	for i := 0; i < count; i++ {
-		point := rand.Intn(p.Face) + 1
-		ret = append(ret, sim_board.Card(strconv.Itoa(point)))
+		ret = append(ret, p.Roll())
 	}
 	return ret
 }
 
+func (p *Dice) Roll() sim_board.Card {
+	if p.weights == nil {
+		return p.faces[rand.Intn(len(p.faces))]
+	}
+	n := rand.Intn(p.totalWeight)
+	for i, w := range p.weights {
+		if n < w {
+			return p.faces[i]
+		}
+		n -= w
+	}
+	return p.faces[len(p.faces)-1]
+}
+
+func (p *Dice) Value(card sim_board.Card) (int, bool) {
+	switch card {
+	case "+":
+		return 1, true
+	case "-":
+		return -1, true
+	}
+	v, err := strconv.Atoi(string(card))
+	return v, err == nil
+}
+
 func GetHTML(card sim_board.Card) (string, bool) {
 	var content string
 	switch card {
@@ -86,7 +131,7 @@ func GetHTML(card sim_board.Card) (string, bool) {
 <div style="display: block; width: 8px; height: 8px; background: #1e1e2f; border-radius: 50%; grid-area: 3/3/4/4;"></div>
 `
 	default:
-		content = fmt.Sprintf(`<div style="font-size: 20px; font-weight: bold">%s</div>`, card)
+		content = fmt.Sprintf(`<div style="font-size: 20px; font-weight: bold">%s</div>`, html.EscapeString(string(card)))
 	}
 	return fmt.Sprintf(`
 <div style="width: 40px; aspect-ratio: 1; background: white; border-radius: 15px; box-shadow: 0 4px 8px rgba(0,0,0,0.2), 0 2px 4px rgba(0,0,0,0.1); display: grid; grid-template-columns: repeat(3, 1fr); place-items: center; overflow: hidden; padding: 7px">
@@ -1,22 +1,113 @@
 package dice
 
 import (
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/KirCute/sim-board"
 )
 
 const Name = "骰子"
 
+const (
+	KindFudge           = "fudge"
+	KindPercentile      = "percentile"
+	KindPercentileUnits = "percentile_units"
+)
+
 type Params struct {
 	CustomName string `json:"custom_name" label:"自定义名称" type:"string"`
 	Face       int    `json:"face" label:"面数" type:"int" min:"1" default:"6"`
+	Kind       string `json:"kind" label:"骰子类型" type:"string"`
+	Faces      string `json:"faces" label:"自定义面" type:"string"`
+	Weights    string `json:"weights" label:"权重" type:"string"`
+}
+
+func splitList(s string) []string {
+	var ret []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+func plainText(s string) bool {
+	for _, c := range s {
+		if !unicode.IsPrint(c) || strings.ContainsRune(`<>&"'`+"`", c) {
+			return false
+		}
+	}
+	return true
 }
 
-func Create(params *Params) *Dice {
-	return &Dice{Params: params}
+func Create(params *Params) (*Dice, error) {
+	ret := &Dice{Params: params}
+	switch {
+	case params.Faces != "":
+		for _, f := range splitList(params.Faces) {
+			if !plainText(f) {
+				return nil, fmt.Errorf("invalid face '%s'", f)
+			}
+			ret.faces = append(ret.faces, sim_board.Card(f))
+		}
+	case params.Kind == KindFudge:
+		ret.faces = []sim_board.Card{"-", "-", "0", "0", "+", "+"}
+	case params.Kind == KindPercentile:
+		for i := 0; i < 100; i += 10 {
+			ret.faces = append(ret.faces, sim_board.Card(fmt.Sprintf("%02d", i)))
+		}
+	case params.Kind == KindPercentileUnits:
+		for i := 0; i < 10; i++ {
+			ret.faces = append(ret.faces, sim_board.Card(strconv.Itoa(i)))
+		}
+	}
+	if len(ret.faces) == 0 {
+		face := params.Face
+		if face <= 0 {
+			face = 6
+		}
+		for i := 1; i <= face; i++ {
+			ret.faces = append(ret.faces, sim_board.Card(strconv.Itoa(i)))
+		}
+	}
+	if params.Weights == "" {
+		return ret, nil
+	}
+	weights := splitList(params.Weights)
+	if len(weights) != len(ret.faces) {
+		return nil, fmt.Errorf("expected %d weights, got %d", len(ret.faces), len(weights))
+	}
+	for _, w := range weights {
+		v, err := strconv.Atoi(w)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid weight '%s'", w)
+		}
+		ret.weights = append(ret.weights, v)
+		ret.totalWeight += v
+	}
+	if ret.totalWeight == 0 {
+		return nil, fmt.Errorf("weights must not all be zero")
+	}
+	return ret, nil
 }
 
 func init() {
 	sim_board.RegisterDeck(Name, reflect.ValueOf(Create), GetHTML)
+	sim_board.RegisterTranslations("en", map[string]string{
+		Name:       "Dice",
+		"%d面%s":    "d%d %s",
+		"自定义名称":    "Custom name",
+		"面数":       "Faces",
+		"骰子类型":     "Dice kind (fudge / percentile / percentile_units)",
+		"自定义面":     "Custom faces (comma separated)",
+		"权重":       "Face weights (comma separated)",
+		"命运%s":     "Fudge %s",
+		"百分%s":     "Percentile %s",
+		"百分%s（个位）": "Percentile %s (units)",
+	})
 }
@@ -19,11 +19,19 @@ func (p *Poker) Type() string {
 }
 
 func (p *Poker) Name() string {
+	return p.LocalizedName(sim_board.DefaultLang)
+}
+
+func (p *Poker) Rename(name string) {
+	p.CustomName = name
+}
+
+func (p *Poker) LocalizedName(lang string) string {
 	if len(p.CustomName) == 0 {
 		if p.Count == 1 && p.CountRank == 13 && p.CountSuit == 4 {
-			return Name
+			return sim_board.Translate(lang, Name)
 		}
-		return fmt.Sprintf("%s%d副%d色%d值", Name, p.Count, p.CountSuit, p.CountRank)
+		return sim_board.Tr(lang, "%s%d副%d色%d值", sim_board.Translate(lang, Name), p.Count, p.CountSuit, p.CountRank)
 	}
 	return p.CustomName
 }
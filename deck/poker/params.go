@@ -43,4 +43,14 @@ func Create(params *Params) *Poker {
 
 func init() {
 	sim_board.RegisterDeck(Name, reflect.ValueOf(Create), GetHTML)
+	sim_board.RegisterTranslations("en", map[string]string{
+		Name:          "Poker",
+		"%s%d副%d色%d值": "%s (%d decks, %d suits, %d ranks)",
+		"自定义名称":       "Custom name",
+		"副数":          "Decks",
+		"花色种类":        "Suits",
+		"数值范围":        "Ranks",
+		"大王总数量":       "Red jokers",
+		"小王总数量":       "Black jokers",
+	})
 }
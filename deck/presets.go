@@ -0,0 +1,62 @@
+package deck
+
+import (
+	"encoding/json"
+
+	"github.com/KirCute/sim-board"
+	"github.com/KirCute/sim-board/deck/chip"
+	"github.com/KirCute/sim-board/deck/poker"
+	"github.com/KirCute/sim-board/deck/uno"
+)
+
+func params(p any) json.RawMessage {
+	data, err := json.Marshal(p)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func init() {
+	sim_board.RegisterPreset(&sim_board.Preset{
+		Name:  "texas_holdem",
+		Title: "德州扑克",
+		Decks: []sim_board.PresetDeck{
+			{Name: poker.Name, Params: params(&poker.Params{Count: 1, CountSuit: 4, CountRank: 13})},
+			{Name: chip.Name, Params: params(&chip.Params{Count5: 100, Count20: 100, Count100: 50, Count500: 20})},
+		},
+		Deal: []sim_board.PresetDeal{{Deck: 0, Num: 2}},
+	})
+	sim_board.RegisterPreset(&sim_board.Preset{
+		Name:  "uno",
+		Title: "经典 UNO",
+		Decks: []sim_board.PresetDeck{
+			{Name: uno.Name, Params: params(&uno.Params{
+				Count:               1,
+				CountColor:          4,
+				CountRank:           10,
+				CountColoredSkip:    2,
+				CountColoredReverse: 2,
+				CountTrans:          4,
+				CountColoredApp2:    2,
+				CountBlackApp4:      4,
+			})},
+		},
+		Deal:  []sim_board.PresetDeal{{Deck: 0, Num: 7}},
+		Board: []sim_board.PresetPlacement{{Deck: 0, Num: 1, X: 0.5, Y: 0.5}},
+	})
+	sim_board.RegisterPreset(&sim_board.Preset{
+		Name:  "dou_dizhu",
+		Title: "斗地主",
+		Decks: []sim_board.PresetDeck{
+			{Name: poker.Name, Params: params(&poker.Params{Count: 1, CountSuit: 4, CountRank: 13, CountRedJoker: 1, CountBlackJoker: 1})},
+		},
+		Deal:  []sim_board.PresetDeal{{Deck: 0, Num: 17}},
+		Board: []sim_board.PresetPlacement{{Deck: 0, Num: 3, X: 0.47, Y: 0.5}},
+	})
+	sim_board.RegisterTranslations("en", map[string]string{
+		"德州扑克":   "Texas Hold'em",
+		"经典 UNO": "Classic UNO",
+		"斗地主":    "Dou Dizhu",
+	})
+}
@@ -25,6 +25,10 @@ func (p *Uno) Name() string {
 	return p.CustomName
 }
 
+func (p *Uno) Rename(name string) {
+	p.CustomName = name
+}
+
 func (p *Uno) RestLen() int {
 	return len(p.rest)
 }
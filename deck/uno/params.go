@@ -125,4 +125,33 @@ func Create(params *Params) *Uno {
 
 func init() {
 	sim_board.RegisterDeck(Name, reflect.ValueOf(Create), GetHTML)
+	sim_board.RegisterTranslations("en", map[string]string{
+		"自定义名称":       "Custom name",
+		"副数":          "Decks",
+		"颜色种类":        "Colors",
+		"数值范围":        "Ranks",
+		"每种颜色跳过卡数量":   "Skip cards per color",
+		"无色跳过总数量":     "Wild skip cards",
+		"每种颜色反转卡数量":   "Reverse cards per color",
+		"无色反转卡总数量":    "Wild reverse cards",
+		"万能卡总数量":      "Wild cards",
+		"每种颜色+2卡数量":   "+2 cards per color",
+		"无色+2卡总数量":    "Wild +2 cards",
+		"每种颜色+4卡数量":   "+4 cards per color",
+		"无色+4卡总数量":    "Wild +4 cards",
+		"每种颜色+6卡数量":   "+6 cards per color",
+		"无色+6卡总数量":    "Wild +6 cards",
+		"每种颜色+8卡数量":   "+8 cards per color",
+		"无色+8卡总数量":    "Wild +8 cards",
+		"每种颜色+10卡数量":  "+10 cards per color",
+		"无色+10卡总数量":   "Wild +10 cards",
+		"每种颜色全场跳过卡数量": "Skip-all cards per color",
+		"无色全场跳过卡总数量":  "Wild skip-all cards",
+		"每种颜色全弃卡数量":   "Discard-all cards per color",
+		"无色全弃卡总数量":    "Wild discard-all cards",
+		"每种颜色交换手牌卡数量": "Swap-hands cards per color",
+		"无色交换手牌卡总数量":  "Wild swap-hands cards",
+		"每种颜色空白卡数量":   "Blank cards per color",
+		"无色空白卡总数量":    "Wild blank cards",
+	})
 }
@@ -0,0 +1,126 @@
+package sim_board
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+type roomDeck struct {
+	Deck
+	id      int
+	version uint64
+	spec    AddDeckArgs
+}
+
+func (d *roomDeck) name(lang string) string {
+	if ld, ok := d.Deck.(LocalizedDeck); ok {
+		return ld.LocalizedName(lang)
+	}
+	return Translate(lang, d.Deck.Name())
+}
+
+func (r *Room) deck(id int) (*roomDeck, bool) {
+	for _, d := range r.decks {
+		if d.id == id {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+func (r *Room) addDeck(d Deck, spec AddDeckArgs) *roomDeck {
+	rd := &roomDeck{Deck: d, id: r.nextDeckID, spec: spec}
+	r.nextDeckID++
+	r.decks = append(r.decks, rd)
+	return rd
+}
+
+func (r *Room) returnCard(card DeckCard) {
+	if d, ok := r.deck(card.DeckId); ok {
+		d.Return(card.Card)
+		d.version++
+	}
+}
+
+type DeckArgs struct {
+	Deck int `json:"deck"`
+}
+
+func (r *Room) handleRemoveDeck(player string, args DeckArgs) (any, error) {
+	if _, ok := r.deck(args.Deck); !ok {
+		return nil, newError("牌堆不存在")
+	}
+	r.recallDeck(args.Deck)
+	for i, d := range r.decks {
+		if d.id == args.Deck {
+			r.decks = append(r.decks[:i], r.decks[i+1:]...)
+			break
+		}
+	}
+	r.broadcast()
+	return nil, nil
+}
+
+type ReconfigureDeckArgs struct {
+	Deck   int             `json:"deck"`
+	Params json.RawMessage `json:"params"`
+}
+
+type ReconfigureDeckResult struct {
+	Recalled bool `json:"recalled"`
+}
+
+func mergeParams(old, patch json.RawMessage) (json.RawMessage, map[string]any, error) {
+	merged := make(map[string]any)
+	if len(old) > 0 {
+		if err := json.Unmarshal(old, &merged); err != nil {
+			return nil, nil, err
+		}
+	}
+	changes := make(map[string]any)
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, nil, err
+	}
+	for k, v := range changes {
+		if reflect.DeepEqual(merged[k], v) {
+			delete(changes, k)
+			continue
+		}
+		merged[k] = v
+	}
+	data, err := json.Marshal(merged)
+	return data, changes, err
+}
+
+func (r *Room) handleReconfigureDeck(player string, args ReconfigureDeckArgs) (any, error) {
+	d, ok := r.deck(args.Deck)
+	if !ok {
+		return nil, newError("牌堆不存在")
+	}
+	params, changes, err := mergeParams(d.spec.Params, args.Params)
+	if err != nil {
+		return nil, newError("请求格式错误")
+	}
+	ret := &ReconfigureDeckResult{}
+	if name, ok := changes["custom_name"].(string); ok && len(changes) == 1 {
+		rd, ok := d.Deck.(Renamable)
+		if !ok {
+			return nil, newError("该牌堆不支持重命名")
+		}
+		rd.Rename(name)
+		changes = nil
+	}
+	if len(changes) > 0 {
+		nd, err := NewDeck(d.spec.Name, params)
+		if err != nil {
+			return nil, newError("添加牌堆失败：%v", err.Error())
+		}
+		r.recallDeck(d.id)
+		d.Deck = nd
+		ret.Recalled = true
+	}
+	d.spec.Params = params
+	d.version++
+	r.broadcast()
+	return ret, nil
+}
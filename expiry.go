@@ -0,0 +1,166 @@
+package sim_board
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ExpiryResponse struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	Remaining int64     `json:"remaining"`
+}
+
+type RoomClosedResponse struct {
+	Room   string `json:"room"`
+	Reason string `json:"reason"`
+}
+
+type RoomArchive struct {
+	Name      string                      `json:"name"`
+	Host      string                      `json:"host"`
+	CreatedAt time.Time                   `json:"created_at"`
+	ClosedAt  time.Time                   `json:"closed_at"`
+	Reason    string                      `json:"reason"`
+	Players   []string                    `json:"players"`
+	Board     map[string]*PublicCard      `json:"board"`
+	Hole      map[string]map[DeckCard]int `json:"hole"`
+	Decks     []MarshaledDeck             `json:"decks"`
+	RoomMeta
+}
+
+func (r *Room) ttl() time.Duration {
+	if r.meta.TTL > 0 {
+		return time.Duration(r.meta.TTL) * time.Second
+	}
+	return time.Duration(config.RoomTTL)
+}
+
+func (r *Room) touchExpiry() {
+	r.expiresAt = time.Now().Add(r.ttl())
+	if r.expiryWarned {
+		r.expiryWarned = false
+		r.sendExpiry("expiry")
+	}
+}
+
+func (r *Room) sendExpiry(typ string) {
+	msg := &ServerMessage{Type: typ, Data: &ExpiryResponse{
+		ExpiresAt: r.expiresAt,
+		Remaining: int64(time.Until(r.expiresAt).Seconds()),
+	}}
+	for player := range r.hole {
+		r.sendMsgTo(player, msg)
+	}
+}
+
+func (r *Room) checkExpiry() bool {
+	remaining := time.Until(r.expiresAt)
+	if remaining <= 0 {
+		return true
+	}
+	if !r.expiryWarned && remaining <= time.Duration(config.RoomExpiryWarning) {
+		r.expiryWarned = true
+		r.sendExpiry("expiring")
+	}
+	return false
+}
+
+func (r *Room) handleKeepAlive() (any, error) {
+	r.expiryWarned = true
+	r.touchExpiry()
+	return nil, nil
+}
+
+type SetTTLArgs struct {
+	TTL int64 `json:"ttl"`
+}
+
+func (r *Room) handleSetTTL(player string, args SetTTLArgs) (any, error) {
+	if err := r.requireHost(player); err != nil {
+		return nil, err
+	}
+	if args.TTL < 0 {
+		return nil, newError("过期时间无效")
+	}
+	r.meta.TTL = args.TTL
+	r.expiryWarned = true
+	r.touchExpiry()
+	r.publishSummary()
+	return nil, nil
+}
+
+func (r *Room) handleCloseRoom(player string) (any, error) {
+	if err := r.requireHost(player); err != nil {
+		return nil, err
+	}
+	r.closeReason = "closed"
+	return nil, nil
+}
+
+func (r *Room) shutdown(reason string) {
+	RemoveRoom(r.name)
+	close(r.done)
+	r.closeScript()
+	closed := &ServerMessage{Type: "room_closed", Data: &RoomClosedResponse{Room: r.name, Reason: reason}}
+	for player, conns := range r.conn {
+		r.sendMsgTo(player, closed)
+		for _, conn := range conns {
+			conn.detach(true)
+		}
+	}
+	r.conn = make(map[string][]*Conn)
+	for {
+		select {
+		case join := <-r.joinChan:
+			join.fail(newError("房间不存在"))
+		case msg := <-r.cmdChan:
+			if msg.reply != nil {
+				msg.reply <- &CommandResult{Err: newError("房间不存在")}
+			} else if msg.conn != nil {
+				writeError(msg.conn, r.playerLang(msg.Player), msg.ReqID, "房间不存在")
+			}
+		case <-r.quitChan:
+		default:
+			r.archive(reason)
+			return
+		}
+	}
+}
+
+func (r *Room) archive(reason string) {
+	if config.ArchiveDir == "" {
+		return
+	}
+	data, err := json.Marshal(&RoomArchive{
+		Name:      r.name,
+		Host:      r.host,
+		CreatedAt: r.createdAt,
+		ClosedAt:  time.Now(),
+		Reason:    reason,
+		Players:   r.players,
+		Board:     r.board,
+		Hole:      r.hole,
+		Decks:     r.marshalDeck(DefaultLang),
+		RoomMeta:  r.meta,
+	})
+	if err != nil {
+		logrus.Errorf("failed to marshal archive of room '%s': %+v", r.name, err)
+		return
+	}
+	if err = os.MkdirAll(config.ArchiveDir, 0o755); err != nil {
+		logrus.Errorf("failed to create archive dir %s: %+v", config.ArchiveDir, err)
+		return
+	}
+	file := filepath.Join(config.ArchiveDir, fmt.Sprintf("%s-%d.json", url.PathEscape(r.name), time.Now().Unix()))
+	if err = os.WriteFile(file, data, 0o644); err != nil {
+		logrus.Errorf("failed to archive room '%s' to %s: %+v", r.name, file, err)
+		return
+	}
+	logrus.Infof("archived room '%s' to %s", r.name, file)
+}
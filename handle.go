@@ -2,121 +2,162 @@ package sim_board
 
 import (
 	"encoding/json"
-	"fmt"
-	"math/rand"
+	"errors"
 
-	"github.com/google/uuid"
-	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 )
 
 type MarshaledDeck struct {
+	ID      int    `json:"id"`
 	Type    string `json:"type"`
 	Name    string `json:"name"`
 	MaxLen  int    `json:"max_len"`
 	RestLen int    `json:"rest_len"`
+	Version uint64 `json:"version"`
 }
 
-func (r *Room) marshalDeck() []MarshaledDeck {
+func (r *Room) marshalDeck(lang string) []MarshaledDeck {
 	ret := make([]MarshaledDeck, 0, len(r.decks))
 	for _, d := range r.decks {
 		ret = append(ret, MarshaledDeck{
+			ID:      d.id,
 			Type:    d.Type(),
-			Name:    d.Name(),
+			Name:    d.name(lang),
 			MaxLen:  d.MaxLen(),
 			RestLen: d.RestLen(),
+			Version: d.version,
 		})
 	}
 	return ret
 }
 
 type BroadcastResponse struct {
-	Board   map[string]*PublicCard `json:"board"`
-	Hole    map[DeckCard]int       `json:"hole"`
-	Decks   []MarshaledDeck        `json:"decks"`
-	Players []string               `json:"players"`
+	Board        map[string]*PublicCard `json:"board"`
+	Hole         map[DeckCard]int       `json:"hole"`
+	Decks        []MarshaledDeck        `json:"decks"`
+	Players      []string               `json:"players"`
+	Version      uint64                 `json:"version"`
+	HandVersions map[string]uint64      `json:"hand_versions"`
+	Presence     map[string]*Presence   `json:"presence"`
+	Zones        []ZoneState            `json:"zones,omitempty"`
+	Pots         map[int]int            `json:"pots,omitempty"`
+	Rolls        []*RollRecord          `json:"rolls,omitempty"`
 }
 
-func (r *Room) makeBroadcastResp() *BroadcastResponse {
+func (r *Room) makeBroadcastResp(player string) *BroadcastResponse {
+	board, zones := r.makeBoard(player)
 	return &BroadcastResponse{
-		Board:   r.board,
-		Decks:   r.marshalDeck(),
-		Players: r.players,
+		Board:        board,
+		Hole:         r.hole[player],
+		Decks:        r.marshalDeck(r.playerLang(player)),
+		Players:      r.players,
+		Version:      r.version,
+		HandVersions: r.handVersions,
+		Presence:     r.presence,
+		Zones:        zones,
+		Pots:         r.pots(),
+		Rolls:        r.rolls,
 	}
 }
 
 func (r *Room) broadcast(except ...string) {
-	ret := r.makeBroadcastResp()
-	for player, hole := range r.hole {
+	r.version++
+	r.publishSummary()
+	for player := range r.hole {
 		if SliceContains(except, player) {
 			continue
 		}
-		ret.Hole = hole
-		msg := &ServerMessage{Type: "broadcast", Data: ret}
+		msg := &ServerMessage{Type: "broadcast", Data: r.makeBroadcastResp(player)}
 		r.sendMsgTo(player, msg)
 	}
 }
 
 type WelcomeResponse struct {
+	Room           RoomSummary                 `json:"room"`
 	Broadcast      *BroadcastResponse          `json:"broadcast"`
 	AvailableDecks map[string][]map[string]any `json:"available_decks"`
+	DeckTitles     map[string]string           `json:"deck_titles"`
+	Lang           string                      `json:"lang"`
+	Session        string                      `json:"session,omitempty"`
 }
 
-func (r *Room) handleWelcome(player string) {
-	b := r.makeBroadcastResp()
-	b.Hole = r.hole[player]
+func (r *Room) handleWelcome(player, reqID, session string) {
+	r.broadcast(player)
+	lang := r.playerLang(player)
 	ret := &WelcomeResponse{
-		Broadcast:      b,
-		AvailableDecks: GetAllAvailableDecks(),
+		Room:           r.Summary(),
+		Broadcast:      r.makeBroadcastResp(player),
+		AvailableDecks: GetAllAvailableDecks(lang),
+		DeckTitles:     GetDeckTitles(lang),
+		Lang:           lang,
+		Session:        session,
 	}
-	r.sendMsgTo(player, &ServerMessage{Type: "welcome", Data: ret})
-	r.broadcast(player)
-}
-
-func getRandomPos() (float32, float32) {
-	x := rand.Float32()/2.0 + 0.25
-	y := rand.Float32()/2.0 + 0.25
-	return x, y
+	r.sendMsgTo(player, &ServerMessage{Type: "welcome", ReqID: reqID, Data: ret})
 }
 
 type DrawArgs struct {
 	Deck   int    `json:"deck"`
 	Num    int    `json:"num"`
 	Target string `json:"target"`
+	Zone   string `json:"zone"`
 }
 
-func (r *Room) handleDraw(player string, args DrawArgs) {
-	if args.Deck >= len(r.decks) {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: "牌堆不存在"})
-		return
+type DrawResult struct {
+	Cards []DeckCard `json:"cards"`
+	IDs   []string   `json:"ids,omitempty"`
+}
+
+func (r *Room) handleDraw(player string, args DrawArgs) (any, error) {
+	d, ok := r.deck(args.Deck)
+	if !ok {
+		return nil, newError("牌堆不存在")
+	}
+	if err := checkDealNum(d, args.Num); err != nil {
+		return nil, err
 	}
-	d := r.decks[args.Deck]
 	if d.RestLen() >= 0 && args.Num > d.RestLen() {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: "数量不足"})
-		return
+		return nil, newError("数量不足")
 	}
 	hole, ok := r.hole[args.Target]
 	if !ok && args.Target != "" {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: "目标不存在"})
-		return
+		return nil, newError("目标不存在")
+	}
+	var zone *Zone
+	if args.Target == "" && args.Zone != "" {
+		if zone, ok = r.zone(args.Zone); !ok {
+			return nil, newError("区域不存在")
+		}
+		if err := r.checkZone(zone, args.Num); err != nil {
+			return nil, err
+		}
 	}
 	cards := d.Draw(args.Num)
+	r.touchDeck(args.Deck)
+	ret := &DrawResult{Cards: make([]DeckCard, 0, len(cards))}
+	for _, card := range cards {
+		ret.Cards = append(ret.Cards, DeckCard{DeckId: args.Deck, Card: card})
+	}
 	if args.Target == "" {
 		for _, card := range cards {
-			x, y := getRandomPos()
-			r.board[uuid.NewString()] = &PublicCard{
-				Card: DeckCard{
-					DeckId: args.Deck,
-					Card:   card,
-				},
-				X:    x,
-				Y:    y,
-				OpID: 0,
-				PlID: r.placeCnter,
+			x, y := getRandomPos(zone)
+			dc := DeckCard{DeckId: args.Deck, Card: card}
+			var id string
+			if zone != nil {
+				id, _ = r.placeCardIn(dc, x, y, zone)
+			} else {
+				id, _ = r.placeCard(dc, x, y)
+			}
+			ret.IDs = append(ret.IDs, id)
+		}
+		if zone != nil && !zone.visibleTo(player) {
+			for i := range ret.Cards {
+				ret.Cards[i].Card = ""
 			}
-			r.placeCnter++
+		} else if dd, ok := d.Deck.(DiceDeck); ok && (zone == nil || zone.Visibility == ZonePublic) {
+			r.recordRoll(&RollRecord{Player: player, Deck: &args.Deck, Faces: cards, Total: diceTotal(dd, cards)})
 		}
 	} else {
+		r.touchHand(args.Target)
 		for _, card := range cards {
 			dc := DeckCard{
 				DeckId: args.Deck,
@@ -129,34 +170,41 @@ func (r *Room) handleDraw(player string, args DrawArgs) {
 		}
 	}
 	r.broadcast()
+	return ret, nil
 }
 
 type AnnounceArgs struct {
 	DeckCard DeckCard `json:"card"`
 	X        float32  `json:"x"`
 	Y        float32  `json:"y"`
+	Lock     bool     `json:"lock"`
 }
 
-func (r *Room) handleAnnounce(player string, args AnnounceArgs) {
+func (r *Room) handleAnnounce(player string, args AnnounceArgs) (any, error) {
 	hole := r.hole[player]
 	if i, ok := hole[args.DeckCard]; !ok || i <= 0 {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: "手牌余量不足"})
-		return
+		return nil, newError("手牌余量不足")
+	}
+	if err := r.checkZone(r.zoneAt(args.X, args.Y), 1); err != nil {
+		return nil, err
 	}
 	if hole[args.DeckCard] <= 1 {
 		delete(hole, args.DeckCard)
 	} else {
 		hole[args.DeckCard]--
 	}
-	r.board[uuid.NewString()] = &PublicCard{
-		Card: args.DeckCard,
-		X:    args.X,
-		Y:    args.Y,
-		OpID: 0,
-		PlID: r.placeCnter,
+	r.touchHand(player)
+	id, card := r.placeCard(args.DeckCard, args.X, args.Y)
+	if args.Lock {
+		card.Owner = player
 	}
-	r.placeCnter++
 	r.broadcast()
+	return &BoardCardResult{ID: id, Card: r.viewCard(player, card)}, nil
+}
+
+type BoardCardResult struct {
+	ID   string      `json:"id"`
+	Card *PublicCard `json:"card"`
 }
 
 type CollectArgs struct {
@@ -164,91 +212,99 @@ type CollectArgs struct {
 	OpID uint   `json:"op_id"`
 }
 
-func (r *Room) handleCollect(player string, args CollectArgs) {
+func (r *Room) handleCollect(player string, args CollectArgs) (any, error) {
 	card, ok := r.board[args.ID]
 	if !ok {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: "公共牌不存在"})
-		return
+		return nil, newError("公共牌不存在")
 	}
 	if card.OpID != args.OpID {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: "操作超时"})
-		return
+		return nil, newConflict("操作超时")
+	}
+	if err := r.checkLock(player, card); err != nil {
+		return nil, err
 	}
 	if _, ok = r.hole[player][card.Card]; ok {
 		r.hole[player][card.Card]++
 	} else {
 		r.hole[player][card.Card] = 1
 	}
+	r.touchHand(player)
 	delete(r.board, args.ID)
 	r.broadcast()
+	return card.Card, nil
 }
 
-func (r *Room) handleDiscardBoard(player string, args CollectArgs) {
+func (r *Room) handleDiscardBoard(player string, args CollectArgs) (any, error) {
 	card, ok := r.board[args.ID]
 	if !ok {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: "公共牌不存在"})
-		return
+		return nil, newError("公共牌不存在")
 	}
 	if card.OpID != args.OpID {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: "操作超时"})
-		return
+		return nil, newConflict("操作超时")
 	}
-	if card.Card.DeckId >= len(r.decks) {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: "牌堆不存在"})
-		return
+	if err := r.checkLock(player, card); err != nil {
+		return nil, err
+	}
+	if _, ok = r.deck(card.Card.DeckId); !ok {
+		return nil, newError("牌堆不存在")
 	}
-	r.decks[card.Card.DeckId].Return(card.Card.Card)
+	r.returnCard(card.Card)
 	delete(r.board, args.ID)
 	r.broadcast()
+	return nil, nil
 }
 
-func (r *Room) handleDiscardHole(player string, card DeckCard) {
+func (r *Room) handleDiscardHole(player string, card DeckCard) (any, error) {
 	hole := r.hole[player]
 	if i, ok := hole[card]; !ok || i <= 0 {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: "手牌余量不足"})
-		return
+		return nil, newError("手牌余量不足")
 	}
-	if card.DeckId >= len(r.decks) {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: "牌堆不存在"})
-		return
+	if _, ok := r.deck(card.DeckId); !ok {
+		return nil, newError("牌堆不存在")
 	}
 	if hole[card] <= 1 {
 		delete(hole, card)
 	} else {
 		hole[card]--
 	}
-	r.decks[card.DeckId].Return(card.Card)
+	r.returnCard(card)
+	r.touchHand(player)
 	r.broadcast()
+	return nil, nil
 }
 
-func (r *Room) handleReset() {
-	for id, card := range r.board {
-		r.decks[card.Card.DeckId].Return(card.Card.Card)
-		delete(r.board, id)
-	}
-	for _, hole := range r.hole {
-		for card, cnt := range hole {
-			for i := 0; i < cnt; i++ {
-				r.decks[card.DeckId].Return(card.Card)
-			}
-			delete(hole, card)
+func (r *Room) returnHand(player string) {
+	hole := r.hole[player]
+	for card, cnt := range hole {
+		for i := 0; i < cnt; i++ {
+			r.returnCard(card)
 		}
+		delete(hole, card)
 	}
-	r.placeCnter = 0
-	r.broadcast()
+	r.touchHand(player)
 }
 
-func (r *Room) handleAllCollect(player string) {
+func (r *Room) handleAllCollect(player string) (any, error) {
 	hole := r.hole[player]
 	for id, card := range r.board {
+		if r.checkLock(player, card) != nil {
+			continue
+		}
+		if z, ok := r.zone(card.Zone); ok && !z.visibleTo(player) {
+			continue
+		}
 		if _, ok := hole[card.Card]; !ok {
 			hole[card.Card] = 0
 		}
 		hole[card.Card]++
 		delete(r.board, id)
 	}
-	r.placeCnter = 0
+	r.touchHand(player)
+	if len(r.board) == 0 {
+		r.placeCnter = 0
+	}
 	r.broadcast()
+	return nil, nil
 }
 
 type AddDeckArgs struct {
@@ -256,14 +312,18 @@ type AddDeckArgs struct {
 	Params json.RawMessage `json:"params"`
 }
 
-func (r *Room) handleAddDeck(player string, args AddDeckArgs) {
+type AddDeckResult struct {
+	Deck int `json:"deck"`
+}
+
+func (r *Room) handleAddDeck(player string, args AddDeckArgs) (any, error) {
 	d, err := NewDeck(args.Name, args.Params)
 	if err != nil {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: fmt.Sprintf("添加牌堆失败：%v", err.Error())})
-		return
+		return nil, newError("添加牌堆失败：%v", err.Error())
 	}
-	r.decks = append(r.decks, d)
+	rd := r.addDeck(d, args)
 	r.broadcast()
+	return &AddDeckResult{Deck: rd.id}, nil
 }
 
 type MoveArgs struct {
@@ -271,36 +331,94 @@ type MoveArgs struct {
 	OpID uint    `json:"op_id"`
 	X    float32 `json:"x"`
 	Y    float32 `json:"y"`
+	Zone string  `json:"zone"`
 }
 
-func (r *Room) handleMove(player string, args MoveArgs) {
+func (r *Room) handleMove(player string, args MoveArgs) (any, error) {
 	card, ok := r.board[args.ID]
 	if !ok {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: "公共牌不存在"})
-		return
+		return nil, newError("公共牌不存在")
 	}
 	if card.OpID != args.OpID {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: fmt.Sprintf("操作超时, src=%d, dst=%d", args.OpID, card.OpID)})
-		return
+		return nil, newConflict("操作超时, src=%d, dst=%d", args.OpID, card.OpID)
+	}
+	if err := r.checkLock(player, card); err != nil {
+		return nil, err
+	}
+	var zone *Zone
+	if args.Zone != "" {
+		if zone, ok = r.zone(args.Zone); !ok {
+			return nil, newError("区域不存在")
+		}
+		args.X, args.Y = getRandomPos(zone)
+	} else {
+		zone = r.zoneAt(min(max(args.X, .0), 1.0), min(max(args.Y, .0), 1.0))
+	}
+	if zone != nil && zone.ID != card.Zone {
+		if err := r.checkZone(zone, 1); err != nil {
+			return nil, err
+		}
 	}
 	card.X = min(max(args.X, .0), 1.0)
 	card.Y = min(max(args.Y, .0), 1.0)
+	card.Zone = ""
+	if zone != nil {
+		card.Zone = zone.ID
+	}
 	card.OpID++
 	card.PlID = r.placeCnter
+	card.Z = r.topZ()
 	r.placeCnter++
 	r.broadcast()
+	return &BoardCardResult{ID: args.ID, Card: r.viewCard(player, card)}, nil
 }
 
-func (r *Room) sendMsgTo(player string, msg *ServerMessage) {
+func (r *Room) playerLang(player string) string {
+	if lang, ok := r.lang[player]; ok {
+		return lang
+	}
+	return DefaultLang
+}
+
+func (r *Room) reply(msg *ClientMessage, result any, err error) {
+	if msg.reply != nil {
+		msg.reply <- r.makeResult(msg.Player, result, err)
+		return
+	}
+	var resp *ServerMessage
+	var ce *conflictError
+	if errors.As(err, &ce) {
+		resp = &ServerMessage{Type: "conflict", ReqID: msg.ReqID, Data: &ConflictResponse{
+			Message: LocalizeError(err, r.playerLang(msg.Player)),
+			State:   r.makeBroadcastResp(msg.Player),
+		}}
+	} else if err != nil {
+		resp = &ServerMessage{Type: "error", ReqID: msg.ReqID, Data: LocalizeError(err, r.playerLang(msg.Player))}
+	} else if msg.ReqID != "" {
+		resp = &ServerMessage{Type: "ack", ReqID: msg.ReqID, Data: result}
+	} else {
+		return
+	}
+	if msg.conn == nil {
+		r.sendMsgTo(msg.Player, resp)
+		return
+	}
+	data, _ := marshalServerMessage(msg.Player, resp)
+	msg.conn.Send(data)
+}
+
+func marshalServerMessage(player string, msg *ServerMessage) ([]byte, error) {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		logrus.Errorf("write message to %s failed due to marshal error, err=%v, msg_type=%s", player, err, msg.Type)
-		msg = &ServerMessage{Type: "fatal", Data: err.Error()}
+		data, _ = json.Marshal(&ServerMessage{Type: "fatal", Data: err.Error()})
 	}
+	return data, err
+}
+
+func (r *Room) sendMsgTo(player string, msg *ServerMessage) {
+	data, _ := marshalServerMessage(player, msg)
 	for _, conn := range r.conn[player] {
-		err = conn.WriteMessage(websocket.TextMessage, data)
-		if err != nil {
-			logrus.Errorf("write message to %s failed, err=%v, msg=%s", conn.RemoteAddr().String(), err, string(data))
-		}
+		conn.Send(data)
 	}
 }
@@ -0,0 +1,128 @@
+package sim_board
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const DefaultLang = "zh"
+
+var (
+	translations     = make(map[string]map[string]string)
+	translationsLock sync.RWMutex
+)
+
+type LocalizedDeck interface {
+	LocalizedName(lang string) string
+}
+
+func RegisterTranslations(lang string, catalog map[string]string) {
+	translationsLock.Lock()
+	defer translationsLock.Unlock()
+	c, ok := translations[lang]
+	if !ok {
+		c = make(map[string]string, len(catalog))
+		translations[lang] = c
+	}
+	for k, v := range catalog {
+		c[k] = v
+	}
+}
+
+func Translate(lang, text string) string {
+	if lang == DefaultLang {
+		return text
+	}
+	translationsLock.RLock()
+	defer translationsLock.RUnlock()
+	if t, ok := translations[lang][text]; ok {
+		return t
+	}
+	return text
+}
+
+func Tr(lang, format string, args ...any) string {
+	if len(args) == 0 {
+		return Translate(lang, format)
+	}
+	return fmt.Sprintf(Translate(lang, format), args...)
+}
+
+func SupportedLang(lang string) bool {
+	if lang == DefaultLang {
+		return true
+	}
+	translationsLock.RLock()
+	defer translationsLock.RUnlock()
+	_, ok := translations[lang]
+	return ok
+}
+
+func NormalizeLang(lang string) (string, bool) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	base, _, _ := strings.Cut(strings.ReplaceAll(lang, "_", "-"), "-")
+	if base == "" || !SupportedLang(base) {
+		return DefaultLang, false
+	}
+	return base, true
+}
+
+func NegotiateLang(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if lang, ok := NormalizeLang(tag); ok && q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return DefaultLang
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].lang
+}
+
+type localizedError struct {
+	format string
+	args   []any
+}
+
+func newError(format string, args ...any) error {
+	return &localizedError{format: format, args: args}
+}
+
+func (e *localizedError) Error() string {
+	return e.Localize(DefaultLang)
+}
+
+func (e *localizedError) Localize(lang string) string {
+	return Tr(lang, e.format, e.args...)
+}
+
+type localizer interface {
+	Localize(lang string) string
+}
+
+func LocalizeError(err error, lang string) string {
+	var le localizer
+	if errors.As(err, &le) {
+		return le.Localize(lang)
+	}
+	return err.Error()
+}
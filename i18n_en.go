@@ -0,0 +1,72 @@
+package sim_board
+
+func init() {
+	RegisterTranslations("en", map[string]string{
+		"请求格式错误":                                  "Malformed request",
+		"无效的请求":                                   "Invalid request",
+		"请求错误":                                    "Bad request",
+		"房间名和玩家名不能为空":                             "Room name and player name must not be empty",
+		"房间已存在":                                   "Room already exists",
+		"房间已满":                                    "Room is full",
+		"人数上限无效":                                  "Invalid player limit",
+		"邀请链接无效或已过期":                              "Invite link is invalid or expired",
+		"该房间需要密码":                                 "This room requires a password",
+		"密码错误":                                    "Wrong password",
+		"会话密钥无效":                                  "Invalid session key",
+		"仅房主可以执行此操作":                              "Only the host can do this",
+		"设置密码失败：%v":                               "Failed to set password: %v",
+		"不能踢出房主":                                  "The host cannot be kicked",
+		"请先加入房间":                                  "Join the room first",
+		"你已被禁止进入该房间":                              "You are banned from this room",
+		"未知的手牌处理方式：%s":                            "Unknown hand policy: %s",
+		"过期时间无效":                                  "Invalid expiry time",
+		"房间数量已达上限":                                "Too many rooms",
+		"创建的房间过多":                                 "Too many rooms created from your address",
+		"请求过于频繁，请稍后再试":                            "Too many requests, please slow down",
+		"房间不存在":                                   "Room does not exist",
+		"不存在的模型：%s@%s":                            "No such model: %s@%s",
+		"牌堆不存在":                                   "Deck does not exist",
+		"数量不足":                                    "Not enough cards",
+		"单次最多%d张":                                 "At most %d cards per player at a time",
+		"目标不存在":                                   "Target does not exist",
+		"未知的回收范围：%s":                              "Unknown recall scope: %s",
+		"区域不存在":                                   "Zone does not exist",
+		"区域已满：%s":                                 "Zone is full: %s",
+		"区域名不能为空":                                 "Zone id must not be empty",
+		"区域范围无效":                                  "Invalid zone bounds",
+		"未知的区域可见性：%s":                             "Unknown zone visibility: %s",
+		"区域容量无效":                                  "Invalid zone capacity",
+		"区域数量已达上限":                                "Too many zones",
+		"未选择公共牌":                                  "No board cards selected",
+		"公共牌重复：%s":                                "Duplicate board card: %s",
+		"未知的批量操作：%s":                              "Unknown batch operation: %s",
+		"该牌已被%s锁定":                                "This card is locked by %s",
+		"该牌堆不支持面值操作":                              "This deck does not support value operations",
+		"该牌堆不支持重命名":                               "This deck cannot be renamed",
+		"金额无效":                                    "Invalid amount",
+		"无法凑出该金额：%d":                              "Cannot make up the amount: %d",
+		"面值无效：%s":                                 "Invalid denomination: %s",
+		"无法兑换":                                    "Cannot make change",
+		"奖池为空":                                    "The pot is empty",
+		"玩家重复：%s":                                 "Duplicate player: %s",
+		"骰子表达式无效：%s":                              "Invalid dice notation: %s",
+		"该牌堆不是骰子":                                 "This deck is not a die",
+		"手牌余量不足":                                  "Not enough cards in hand",
+		"公共牌不存在":                                  "Board card does not exist",
+		"操作超时":                                    "Operation is stale",
+		"操作超时, src=%d, dst=%d":                    "Operation is stale, src=%d, dst=%d",
+		"未知的指令：%s":                                "Unknown command: %s",
+		"房间状态已变更, expect=%d, current=%d":          "Room state changed, expect=%d, current=%d",
+		"牌堆状态已变更, deck=%d, expect=%d, current=%d": "Deck state changed, deck=%d, expect=%d, current=%d",
+		"手牌状态已变更, player=%s, expect=%d, current=%d": "Hand state changed, player=%s, expect=%d, current=%d",
+		"添加牌堆失败：%v":                                 "Failed to add deck: %v",
+		"请求超时":                                      "Request timed out",
+		"脚本错误：%v":                                   "Script error: %v",
+		"脚本过大":                                      "Script is too large",
+		"预设不存在：%s":                                  "No such preset: %s",
+		"预设名不能为空":                                   "Preset name must not be empty",
+		"不能覆盖内置预设":                                  "Built-in presets cannot be overwritten",
+		"预设数量已达上限":                                  "Too many presets",
+		"该牌堆无法全部发完":                                 "This deck cannot be dealt out entirely",
+	})
+}
@@ -0,0 +1,160 @@
+package sim_board
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	heartbeatRate    = 1
+	heartbeatBurst   = 3
+	authFailureRate  = 0.2
+	authFailureBurst = 5
+	limiterIdleTTL   = 10 * time.Minute
+)
+
+var errThrottled = newError("请求过于频繁，请稍后再试")
+
+type ipQuota struct {
+	lock  sync.Mutex
+	conns map[string]int
+	rooms map[string]int
+}
+
+var quota = &ipQuota{
+	conns: make(map[string]int),
+	rooms: make(map[string]int),
+}
+
+func acquire(counter map[string]int, ip string, limit int) bool {
+	if limit > 0 && counter[ip] >= limit {
+		return false
+	}
+	counter[ip]++
+	return true
+}
+
+func release(counter map[string]int, ip string) {
+	if counter[ip] <= 1 {
+		delete(counter, ip)
+	} else {
+		counter[ip]--
+	}
+}
+
+func (q *ipQuota) acquireConn(ip string) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return acquire(q.conns, ip, config.MaxConnsPerIP)
+}
+
+func (q *ipQuota) releaseConn(ip string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	release(q.conns, ip)
+}
+
+func (q *ipQuota) acquireRoom(ip string) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return acquire(q.rooms, ip, config.MaxRoomsPerIP)
+}
+
+func (q *ipQuota) releaseRoom(ip string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	release(q.rooms, ip)
+}
+
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (l *rateLimiter) refill() {
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+}
+
+func (l *rateLimiter) allow() bool {
+	if l.rate <= 0 {
+		return true
+	}
+	l.refill()
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+func (l *rateLimiter) ready() bool {
+	if l.rate <= 0 {
+		return true
+	}
+	l.refill()
+	return l.tokens >= 1
+}
+
+type ipLimiters struct {
+	lock       sync.Mutex
+	limiters   map[string]*rateLimiter
+	swept      time.Time
+	newLimiter func() *rateLimiter
+}
+
+var apiLimiters = &ipLimiters{
+	limiters: make(map[string]*rateLimiter),
+	newLimiter: func() *rateLimiter {
+		return newRateLimiter(config.RateLimit, config.RateBurst)
+	},
+}
+
+var authFailures = &ipLimiters{
+	limiters: make(map[string]*rateLimiter),
+	newLimiter: func() *rateLimiter {
+		return newRateLimiter(authFailureRate, authFailureBurst)
+	},
+}
+
+func (l *ipLimiters) get(ip string) *rateLimiter {
+	now := time.Now()
+	if now.Sub(l.swept) >= limiterIdleTTL {
+		for k, v := range l.limiters {
+			if now.Sub(v.last) >= limiterIdleTTL {
+				delete(l.limiters, k)
+			}
+		}
+		l.swept = now
+	}
+	limiter, ok := l.limiters[ip]
+	if !ok {
+		limiter = l.newLimiter()
+		l.limiters[ip] = limiter
+	}
+	return limiter
+}
+
+func (l *ipLimiters) allow(ip string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.get(ip).allow()
+}
+
+func (l *ipLimiters) ready(ip string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.get(ip).ready()
+}
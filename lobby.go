@@ -0,0 +1,74 @@
+package sim_board
+
+import (
+	"sort"
+	"time"
+)
+
+type RoomMeta struct {
+	Title      string `json:"title"`
+	MaxPlayers int    `json:"max_players"`
+	Public     bool   `json:"public"`
+	TTL        int64  `json:"ttl"`
+}
+
+type DeckSummary struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	RestLen int    `json:"rest_len"`
+	MaxLen  int    `json:"max_len"`
+}
+
+type RoomSummary struct {
+	Name      string        `json:"name"`
+	Host      string        `json:"host"`
+	Players   int           `json:"players"`
+	Decks     []DeckSummary `json:"decks"`
+	CreatedAt time.Time     `json:"created_at"`
+	Age       int64         `json:"age"`
+	RoomMeta
+}
+
+func (r *Room) publishSummary() {
+	s := &RoomSummary{
+		Name:      r.name,
+		Host:      r.host,
+		Players:   len(r.players),
+		Decks:     make([]DeckSummary, 0, len(r.decks)),
+		CreatedAt: r.createdAt,
+		RoomMeta:  r.meta,
+	}
+	for _, d := range r.decks {
+		s.Decks = append(s.Decks, DeckSummary{
+			Type:    d.Type(),
+			Name:    d.Name(),
+			RestLen: d.RestLen(),
+			MaxLen:  d.MaxLen(),
+		})
+	}
+	r.summary.Store(s)
+}
+
+func (r *Room) Name() string {
+	return r.name
+}
+
+func (r *Room) Summary() RoomSummary {
+	s := *r.summary.Load()
+	s.Age = int64(time.Since(s.CreatedAt).Seconds())
+	return s
+}
+
+func ListRooms() []RoomSummary {
+	ret := make([]RoomSummary, 0)
+	roomMap.Range(func(_, value any) bool {
+		if s := value.(*Room).Summary(); s.Public {
+			ret = append(ret, s)
+		}
+		return true
+	})
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].CreatedAt.Before(ret[j].CreatedAt)
+	})
+	return ret
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"io/fs"
+	"os"
 
 	"github.com/KirCute/sim-board"
 	_ "github.com/KirCute/sim-board/deck"
@@ -9,9 +13,17 @@ import (
 )
 
 func main() {
+	cfg, err := sim_board.LoadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
 	f, err := fs.Sub(public.Public, "dist")
 	if err != nil {
 		panic(err)
 	}
-	sim_board.Run(f)
+	sim_board.Run(f, cfg)
 }
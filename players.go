@@ -0,0 +1,155 @@
+package sim_board
+
+type HandPolicy string
+
+const (
+	HandPolicyReturn HandPolicy = "return"
+	HandPolicyBoard  HandPolicy = "board"
+	HandPolicyGive   HandPolicy = "give"
+)
+
+type LeaveArgs struct {
+	Policy HandPolicy `json:"policy"`
+	Target string     `json:"target"`
+}
+
+type KickArgs struct {
+	Player string `json:"player"`
+	LeaveArgs
+}
+
+type PlayerLeftResponse struct {
+	Player string     `json:"player"`
+	Reason string     `json:"reason"`
+	By     string     `json:"by"`
+	Policy HandPolicy `json:"policy"`
+	Target string     `json:"target,omitempty"`
+	Host   string     `json:"host"`
+}
+
+func (r *Room) checkLeaveArgs(player string, args *LeaveArgs) error {
+	switch args.Policy {
+	case "":
+		args.Policy = HandPolicyReturn
+	case HandPolicyReturn, HandPolicyBoard:
+	case HandPolicyGive:
+		if _, ok := r.hole[args.Target]; !ok || args.Target == player {
+			return newError("目标不存在")
+		}
+	default:
+		return newError("未知的手牌处理方式：%s", args.Policy)
+	}
+	return nil
+}
+
+func (r *Room) releaseHand(player string, args LeaveArgs) {
+	switch args.Policy {
+	case HandPolicyReturn:
+		r.returnHand(player)
+	case HandPolicyBoard:
+		for card, cnt := range r.hole[player] {
+			for i := 0; i < cnt; i++ {
+				x, y := getRandomPos(nil)
+				r.placeCard(card, x, y)
+			}
+		}
+	case HandPolicyGive:
+		target := r.hole[args.Target]
+		for card, cnt := range r.hole[player] {
+			target[card] += cnt
+		}
+		r.touchHand(args.Target)
+	}
+}
+
+func (r *Room) removePlayer(player, reason, by string, args LeaveArgs) {
+	r.releaseHand(player, args)
+	delete(r.hole, player)
+	delete(r.handVersions, player)
+	delete(r.lang, player)
+	delete(r.presence, player)
+	r.auth.dropSession(player)
+	for _, card := range r.board {
+		if card.Owner == player {
+			card.Owner = ""
+		}
+	}
+	for i, p := range r.players {
+		if p == player {
+			r.players = append(r.players[:i], r.players[i+1:]...)
+			break
+		}
+	}
+	if player == r.host {
+		r.host = ""
+		if len(r.players) > 0 {
+			r.host = r.players[0]
+		}
+	}
+	left := &ServerMessage{Type: "player_left", Data: &PlayerLeftResponse{
+		Player: player,
+		Reason: reason,
+		By:     by,
+		Policy: args.Policy,
+		Target: args.Target,
+		Host:   r.host,
+	}}
+	r.sendMsgTo(player, left)
+	for _, conn := range r.conn[player] {
+		conn.detach(reason != "leave")
+	}
+	delete(r.conn, player)
+	for p := range r.hole {
+		r.sendMsgTo(p, left)
+	}
+	r.broadcast()
+	r.fireScript("player_left", player, reason)
+}
+
+func (r *Room) handleLeave(player string, args LeaveArgs) (any, error) {
+	if err := r.checkLeaveArgs(player, &args); err != nil {
+		return nil, err
+	}
+	r.removePlayer(player, "leave", player, args)
+	return nil, nil
+}
+
+func (r *Room) checkKick(player string, args *KickArgs) error {
+	if err := r.requireHost(player); err != nil {
+		return err
+	}
+	if args.Player == r.host {
+		return newError("不能踢出房主")
+	}
+	if _, ok := r.hole[args.Player]; !ok {
+		return newError("目标不存在")
+	}
+	return r.checkLeaveArgs(args.Player, &args.LeaveArgs)
+}
+
+func (r *Room) handleKick(player string, args KickArgs) (any, error) {
+	if err := r.checkKick(player, &args); err != nil {
+		return nil, err
+	}
+	r.removePlayer(args.Player, "kick", player, args.LeaveArgs)
+	return nil, nil
+}
+
+func (r *Room) handleBan(player string, args KickArgs) (any, error) {
+	if err := r.checkKick(player, &args); err != nil {
+		return nil, err
+	}
+	r.auth.ban(args.Player)
+	r.removePlayer(args.Player, "ban", player, args.LeaveArgs)
+	return nil, nil
+}
+
+func (r *Room) handleUnban(player string, args KickArgs) (any, error) {
+	if err := r.requireHost(player); err != nil {
+		return nil, err
+	}
+	if !r.auth.unban(args.Player) {
+		return nil, newError("目标不存在")
+	}
+	return nil, nil
+}
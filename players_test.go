@@ -0,0 +1,146 @@
+package sim_board
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type testDeckParams struct {
+	Size int `json:"size"`
+}
+
+// testDeck hands out numbered cards; a negative size makes it endless.
+type testDeck struct {
+	size  int
+	drawn int
+}
+
+func newTestDeck(p testDeckParams) *testDeck {
+	return &testDeck{size: p.Size}
+}
+
+func (d *testDeck) Type() string { return "test" }
+func (d *testDeck) Name() string { return "test" }
+
+func (d *testDeck) RestLen() int {
+	if d.size < 0 {
+		return -1
+	}
+	return d.size - d.drawn
+}
+
+func (d *testDeck) MaxLen() int { return d.size }
+
+func (d *testDeck) Return(Card) { d.drawn-- }
+
+func (d *testDeck) Draw(count int) []Card {
+	ret := make([]Card, 0, count)
+	for i := 0; i < count; i++ {
+		d.drawn++
+		ret = append(ret, Card("c"+strconv.Itoa(d.drawn)))
+	}
+	return ret
+}
+
+func init() {
+	RegisterDeck("test", reflect.ValueOf(newTestDeck), nil)
+}
+
+func newTestRoom(t *testing.T, players ...string) *Room {
+	t.Helper()
+	r, err := CreateRoom(t.Name(), players[0], t.Name(), RoomMeta{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { testCloseRoom(t, r) })
+	for _, p := range players {
+		testJoin(t, r, p)
+	}
+	return r
+}
+
+// testCloseRoom closes the room and waits for its actor to stop, so later
+// tests may change config without racing it.
+func testCloseRoom(t *testing.T, r *Room) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, _ = r.Do(ctx, r.Summary().Host, "close_room", nil)
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		t.Errorf("room %s did not close", r.name)
+	}
+}
+
+func testJoin(t *testing.T, r *Room, player string) {
+	t.Helper()
+	if _, err := r.Join(context.Background(), player, DefaultLang); err != nil {
+		t.Fatalf("join %s: %v", player, err)
+	}
+}
+
+func testDo(r *Room, player, command string, args any) (*CommandResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return r.Do(ctx, player, command, args)
+}
+
+func testAddDeck(t *testing.T, r *Room, size int) int {
+	t.Helper()
+	params, _ := json.Marshal(testDeckParams{Size: size})
+	res, err := testDo(r, r.Summary().Host, "add_deck", AddDeckArgs{Name: "test", Params: params})
+	if err != nil {
+		t.Fatalf("add deck: %v", err)
+	}
+	var ret AddDeckResult
+	if err = json.Unmarshal(res.Result, &ret); err != nil {
+		t.Fatal(err)
+	}
+	return ret.Deck
+}
+
+func testState(t *testing.T, r *Room, player string) *BroadcastResponse {
+	t.Helper()
+	res, err := testDo(r, player, "state", struct{}{})
+	if err != nil {
+		t.Fatalf("state of %s: %v", player, err)
+	}
+	var state BroadcastResponse
+	if err = json.Unmarshal(res.State, &state); err != nil {
+		t.Fatal(err)
+	}
+	return &state
+}
+
+func TestHostHandover(t *testing.T) {
+	r := newTestRoom(t, "alice")
+	if _, err := testDo(r, "alice", "leave", LeaveArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	if host := r.Summary().Host; host != "" {
+		t.Fatalf("host = %q after the last player left, want none", host)
+	}
+	testJoin(t, r, "bob")
+	if host := r.Summary().Host; host != "bob" {
+		t.Fatalf("host = %q, want bob", host)
+	}
+	testJoin(t, r, "alice")
+	zone := &Zone{ID: "z", W: 0.5, H: 0.5}
+	if _, err := testDo(r, "alice", "set_zone", zone); err == nil {
+		t.Fatal("former host kept host powers after rejoining")
+	}
+	if _, err := testDo(r, "bob", "set_zone", zone); err != nil {
+		t.Fatalf("new host: %v", err)
+	}
+	if _, err := testDo(r, "bob", "leave", LeaveArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	if host := r.Summary().Host; host != "alice" {
+		t.Fatalf("host = %q, want alice", host)
+	}
+}
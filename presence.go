@@ -0,0 +1,80 @@
+package sim_board
+
+import "time"
+
+const (
+	presenceCheckInterval = 10 * time.Second
+	reconnectGracePeriod  = 30 * time.Second
+	idleTimeout           = 5 * time.Minute
+)
+
+type PresenceState string
+
+const (
+	PresenceOnline       PresenceState = "online"
+	PresenceIdle         PresenceState = "idle"
+	PresenceReconnecting PresenceState = "reconnecting"
+	PresenceOffline      PresenceState = "offline"
+)
+
+type Presence struct {
+	State      PresenceState `json:"state"`
+	Since      time.Time     `json:"since"`
+	LastSeen   time.Time     `json:"last_seen"`
+	LastActive time.Time     `json:"last_active"`
+	Conns      int           `json:"conns"`
+}
+
+type PresenceEvent struct {
+	Player string `json:"player"`
+	*Presence
+}
+
+func (r *Room) setPresence(player string, state PresenceState) {
+	p, ok := r.presence[player]
+	if !ok {
+		now := time.Now()
+		p = &Presence{LastSeen: now, LastActive: now}
+		r.presence[player] = p
+	}
+	p.Conns = len(r.conn[player])
+	if p.State == state {
+		return
+	}
+	p.State = state
+	p.Since = time.Now()
+	event := &ServerMessage{Type: "presence", Data: &PresenceEvent{Player: player, Presence: p}}
+	for other := range r.hole {
+		r.sendMsgTo(other, event)
+	}
+}
+
+func (r *Room) markSeen(player string, active bool) {
+	p, ok := r.presence[player]
+	if !ok {
+		return
+	}
+	p.LastSeen = time.Now()
+	if active {
+		p.LastActive = p.LastSeen
+		if p.State == PresenceIdle {
+			r.setPresence(player, PresenceOnline)
+		}
+	}
+}
+
+func (r *Room) checkPresence() {
+	now := time.Now()
+	for player, p := range r.presence {
+		switch p.State {
+		case PresenceOnline:
+			if now.Sub(p.LastActive) >= idleTimeout {
+				r.setPresence(player, PresenceIdle)
+			}
+		case PresenceReconnecting:
+			if now.Sub(p.Since) >= reconnectGracePeriod {
+				r.setPresence(player, PresenceOffline)
+			}
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package sim_board
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+type PresetDeck struct {
+	Name   string          `json:"name"`
+	Params json.RawMessage `json:"params"`
+}
+
+type PresetDeal struct {
+	Deck int `json:"deck"`
+	Num  int `json:"num"`
+}
+
+type PresetPlacement struct {
+	Deck int     `json:"deck"`
+	Num  int     `json:"num"`
+	X    float32 `json:"x"`
+	Y    float32 `json:"y"`
+}
+
+type Preset struct {
+	Name    string            `json:"name"`
+	Title   string            `json:"title"`
+	BuiltIn bool              `json:"builtin"`
+	Decks   []PresetDeck      `json:"decks"`
+	Deal    []PresetDeal      `json:"deal"`
+	Board   []PresetPlacement `json:"board"`
+}
+
+var (
+	presets     = make(map[string]*Preset)
+	presetsLock sync.RWMutex
+)
+
+func RegisterPreset(p *Preset) {
+	presetsLock.Lock()
+	defer presetsLock.Unlock()
+	if _, ok := presets[p.Name]; ok {
+		panic("preset already registered: " + p.Name)
+	}
+	p.BuiltIn = true
+	presets[p.Name] = p
+}
+
+func GetPreset(name string) (*Preset, bool) {
+	presetsLock.RLock()
+	defer presetsLock.RUnlock()
+	p, ok := presets[name]
+	return p, ok
+}
+
+func ListPresets(lang string) []Preset {
+	presetsLock.RLock()
+	ret := make([]Preset, 0, len(presets))
+	for _, p := range presets {
+		c := *p
+		if c.BuiltIn {
+			c.Title = Translate(lang, c.Title)
+		}
+		ret = append(ret, c)
+	}
+	presetsLock.RUnlock()
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].BuiltIn != ret[j].BuiltIn {
+			return ret[i].BuiltIn
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+func savePreset(p *Preset) error {
+	presetsLock.Lock()
+	defer presetsLock.Unlock()
+	if old, ok := presets[p.Name]; ok && old.BuiltIn {
+		return newError("不能覆盖内置预设")
+	}
+	user := 0
+	for _, old := range presets {
+		if !old.BuiltIn {
+			user++
+		}
+	}
+	if _, ok := presets[p.Name]; !ok && config.MaxPresets > 0 && user >= config.MaxPresets {
+		return newError("预设数量已达上限")
+	}
+	presets[p.Name] = p
+	persistPresets()
+	return nil
+}
+
+func persistPresets() {
+	if config.PresetFile == "" {
+		return
+	}
+	user := make([]*Preset, 0, len(presets))
+	for _, p := range presets {
+		if !p.BuiltIn {
+			user = append(user, p)
+		}
+	}
+	data, err := json.MarshalIndent(user, "", "  ")
+	if err != nil {
+		logrus.Errorf("failed to marshal presets: %+v", err)
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(config.PresetFile), 0o755); err != nil {
+		logrus.Errorf("failed to create preset dir: %+v", err)
+		return
+	}
+	if err = os.WriteFile(config.PresetFile, data, 0o644); err != nil {
+		logrus.Errorf("failed to save presets to %s: %+v", config.PresetFile, err)
+	}
+}
+
+func loadPresets() {
+	if config.PresetFile == "" {
+		return
+	}
+	data, err := os.ReadFile(config.PresetFile)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		logrus.Errorf("failed to read presets from %s: %+v", config.PresetFile, err)
+		return
+	}
+	var user []*Preset
+	if err = json.Unmarshal(data, &user); err != nil {
+		logrus.Errorf("failed to parse presets from %s: %+v", config.PresetFile, err)
+		return
+	}
+	presetsLock.Lock()
+	defer presetsLock.Unlock()
+	for _, p := range user {
+		if old, ok := presets[p.Name]; ok && old.BuiltIn {
+			continue
+		}
+		p.BuiltIn = false
+		presets[p.Name] = p
+	}
+	logrus.Infof("loaded %d presets from %s", len(user), config.PresetFile)
+}
+
+type ApplyPresetArgs struct {
+	Name string `json:"name"`
+}
+
+type ApplyPresetResult struct {
+	Decks []int `json:"decks"`
+}
+
+func (r *Room) handleApplyPreset(player string, args ApplyPresetArgs) (any, error) {
+	if err := r.requireHost(player); err != nil {
+		return nil, err
+	}
+	p, ok := GetPreset(args.Name)
+	if !ok {
+		return nil, newError("预设不存在：%s", args.Name)
+	}
+	decks := make([]Deck, 0, len(p.Decks))
+	for _, pd := range p.Decks {
+		d, err := NewDeck(pd.Name, pd.Params)
+		if err != nil {
+			return nil, newError("添加牌堆失败：%v", err.Error())
+		}
+		decks = append(decks, d)
+	}
+	need := make([]int, len(decks))
+	for _, deal := range p.Deal {
+		if deal.Deck < 0 || deal.Deck >= len(decks) {
+			return nil, newError("牌堆不存在")
+		}
+		need[deal.Deck] += deal.Num * len(r.players)
+	}
+	for _, pl := range p.Board {
+		if pl.Deck < 0 || pl.Deck >= len(decks) {
+			return nil, newError("牌堆不存在")
+		}
+		need[pl.Deck] += pl.Num
+	}
+	for i, d := range decks {
+		if d.RestLen() >= 0 && need[i] > d.RestLen() {
+			return nil, newError("数量不足")
+		}
+	}
+	added := make([]*roomDeck, 0, len(decks))
+	ret := &ApplyPresetResult{}
+	for i, d := range decks {
+		rd := r.addDeck(d, AddDeckArgs(p.Decks[i]))
+		added = append(added, rd)
+		ret.Decks = append(ret.Decks, rd.id)
+	}
+	for _, deal := range p.Deal {
+		r.dealCards(added[deal.Deck], deal.Num, r.players)
+	}
+	for _, pl := range p.Board {
+		d := added[pl.Deck]
+		for i, card := range d.Draw(pl.Num) {
+			r.placeCard(DeckCard{DeckId: d.id, Card: card}, pl.X+float32(i)*0.02, pl.Y)
+		}
+	}
+	for _, d := range added {
+		d.version++
+	}
+	r.broadcast()
+	return ret, nil
+}
+
+type SavePresetArgs struct {
+	Name  string       `json:"name"`
+	Title string       `json:"title"`
+	Deal  []PresetDeal `json:"deal"`
+}
+
+func (r *Room) handleSavePreset(player string, args SavePresetArgs) (any, error) {
+	if err := r.requireHost(player); err != nil {
+		return nil, err
+	}
+	if args.Name == "" {
+		return nil, newError("预设名不能为空")
+	}
+	p := &Preset{Name: args.Name, Title: args.Title, Decks: make([]PresetDeck, 0, len(r.decks))}
+	if p.Title == "" {
+		p.Title = p.Name
+	}
+	index := make(map[int]int, len(r.decks))
+	for i, d := range r.decks {
+		index[d.id] = i
+		p.Decks = append(p.Decks, PresetDeck(d.spec))
+	}
+	for _, deal := range args.Deal {
+		i, ok := index[deal.Deck]
+		if !ok || deal.Num < 0 {
+			return nil, newError("牌堆不存在")
+		}
+		if deal.Num > config.MaxDeal {
+			return nil, newError("单次最多%d张", config.MaxDeal)
+		}
+		p.Deal = append(p.Deal, PresetDeal{Deck: i, Num: deal.Num})
+	}
+	ids := make([]string, 0, len(r.board))
+	for id := range r.board {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return r.board[ids[i]].PlID < r.board[ids[j]].PlID
+	})
+	for _, id := range ids {
+		card := r.board[id]
+		if i, ok := index[card.Card.DeckId]; ok {
+			p.Board = append(p.Board, PresetPlacement{Deck: i, Num: 1, X: card.X, Y: card.Y})
+		}
+	}
+	if err := savePreset(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
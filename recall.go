@@ -0,0 +1,126 @@
+package sim_board
+
+type RecallScope string
+
+const (
+	RecallAll    RecallScope = "all"
+	RecallDeck   RecallScope = "deck"
+	RecallPlayer RecallScope = "player"
+	RecallBoard  RecallScope = "board"
+	RecallHands  RecallScope = "hands"
+)
+
+type RecallArgs struct {
+	Scope  RecallScope `json:"scope"`
+	Deck   int         `json:"deck"`
+	Player string      `json:"player"`
+}
+
+type RecallEvent struct {
+	Scope  RecallScope    `json:"scope"`
+	Deck   *int           `json:"deck,omitempty"`
+	Player string         `json:"player,omitempty"`
+	By     string         `json:"by"`
+	Board  int            `json:"board"`
+	Hands  map[string]int `json:"hands"`
+	Pot    int            `json:"pot,omitempty"`
+}
+
+func (r *Room) recall(board func(*PublicCard) bool, pot func(DeckCard) bool, hand func(string, DeckCard) bool) *RecallEvent {
+	ret := &RecallEvent{Hands: make(map[string]int)}
+	if board != nil {
+		for id, card := range r.board {
+			if board(card) {
+				r.returnCard(card.Card)
+				delete(r.board, id)
+				ret.Board++
+			}
+		}
+	}
+	if pot != nil {
+		for card, cnt := range r.pot {
+			if !pot(card) {
+				continue
+			}
+			for i := 0; i < cnt; i++ {
+				r.returnCard(card)
+			}
+			delete(r.pot, card)
+			ret.Pot += cnt
+		}
+	}
+	if hand != nil {
+		for player, hole := range r.hole {
+			n := 0
+			for card, cnt := range hole {
+				if !hand(player, card) {
+					continue
+				}
+				for i := 0; i < cnt; i++ {
+					r.returnCard(card)
+				}
+				delete(hole, card)
+				n += cnt
+			}
+			if n > 0 {
+				ret.Hands[player] = n
+				r.touchHand(player)
+			}
+		}
+	}
+	if len(r.board) == 0 {
+		r.placeCnter = 0
+	}
+	return ret
+}
+
+func (r *Room) recallDeck(id int) *RecallEvent {
+	return r.recall(func(card *PublicCard) bool {
+		return card.Card.DeckId == id
+	}, func(card DeckCard) bool {
+		return card.DeckId == id
+	}, func(_ string, card DeckCard) bool {
+		return card.DeckId == id
+	})
+}
+
+func (r *Room) handleRecall(player string, args RecallArgs) (any, error) {
+	all := func(*PublicCard) bool { return true }
+	pot := func(DeckCard) bool { return true }
+	var ret *RecallEvent
+	switch args.Scope {
+	case RecallAll, "":
+		args.Scope = RecallAll
+		ret = r.recall(all, pot, func(string, DeckCard) bool { return true })
+	case RecallDeck:
+		if _, ok := r.deck(args.Deck); !ok {
+			return nil, newError("牌堆不存在")
+		}
+		ret = r.recallDeck(args.Deck)
+		ret.Deck = &args.Deck
+	case RecallPlayer:
+		if _, ok := r.hole[args.Player]; !ok {
+			return nil, newError("目标不存在")
+		}
+		ret = r.recall(nil, nil, func(p string, _ DeckCard) bool { return p == args.Player })
+		ret.Player = args.Player
+	case RecallBoard:
+		ret = r.recall(all, pot, nil)
+	case RecallHands:
+		ret = r.recall(nil, nil, func(string, DeckCard) bool { return true })
+	default:
+		return nil, newError("未知的回收范围：%s", args.Scope)
+	}
+	ret.Scope = args.Scope
+	ret.By = player
+	event := &ServerMessage{Type: "recall", Data: ret}
+	for p := range r.hole {
+		r.sendMsgTo(p, event)
+	}
+	r.broadcast()
+	return ret, nil
+}
+
+func (r *Room) handleReset(player string) (any, error) {
+	return r.handleRecall(player, RecallArgs{Scope: RecallAll})
+}
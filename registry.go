@@ -28,8 +28,8 @@ func RegisterDeck(name string, constructor reflect.Value, htmlGetter func(card C
 	if ct.NumIn() != 1 {
 		panic("deck constructor must have exactly one input parameter")
 	}
-	if ct.NumOut() != 1 {
-		panic("deck constructor must have exactly one output parameter")
+	if ct.NumOut() != 1 && (ct.NumOut() != 2 || ct.Out(1) != reflect.TypeOf((*error)(nil)).Elem()) {
+		panic("deck constructor must return the deck and optionally an error")
 	}
 	deckType := ct.Out(0)
 	if deckType.Kind() != reflect.Ptr {
@@ -117,8 +117,11 @@ func NewDeck(name string, param json.RawMessage) (Deck, error) {
 	} else {
 		callArg = arg.Elem()
 	}
-	ret := reg.constructor.Call([]reflect.Value{callArg})[0].Interface().(Deck)
-	return ret, nil
+	out := reg.constructor.Call([]reflect.Value{callArg})
+	if len(out) == 2 && !out[1].IsNil() {
+		return nil, out[1].Interface().(error)
+	}
+	return out[0].Interface().(Deck), nil
 }
 
 func GetCardHTML(deck, card string) (string, bool) {
@@ -129,10 +132,29 @@ func GetCardHTML(deck, card string) (string, bool) {
 	return d.htmlGetter(Card(card))
 }
 
-func GetAllAvailableDecks() map[string][]map[string]any {
+func GetAllAvailableDecks(lang string) map[string][]map[string]any {
 	ret := make(map[string][]map[string]any, len(decks))
 	for deck, reg := range decks {
-		ret[deck] = reg.paramSchema
+		schema := make([]map[string]any, 0, len(reg.paramSchema))
+		for _, desc := range reg.paramSchema {
+			localized := make(map[string]any, len(desc))
+			for k, v := range desc {
+				localized[k] = v
+			}
+			if label, ok := desc["label"].(string); ok {
+				localized["label"] = Translate(lang, label)
+			}
+			schema = append(schema, localized)
+		}
+		ret[deck] = schema
+	}
+	return ret
+}
+
+func GetDeckTitles(lang string) map[string]string {
+	ret := make(map[string]string, len(decks))
+	for deck := range decks {
+		ret[deck] = Translate(lang, deck)
 	}
 	return ret
 }
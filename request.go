@@ -0,0 +1,108 @@
+package sim_board
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+type CommandResult struct {
+	Err    error
+	Result json.RawMessage
+	State  json.RawMessage
+}
+
+func (r *Room) makeResult(player string, result any, err error) *CommandResult {
+	res := &CommandResult{Err: err}
+	if result != nil {
+		res.Result, _ = json.Marshal(result)
+	}
+	if _, ok := r.hole[player]; ok {
+		res.State, _ = json.Marshal(r.makeBroadcastResp(player))
+	}
+	return res
+}
+
+func IsConflict(err error) bool {
+	var ce *conflictError
+	return errors.As(err, &ce)
+}
+
+func (r *Room) await(ctx context.Context, reply chan *CommandResult) (*CommandResult, error) {
+	select {
+	case res := <-reply:
+		return res, res.Err
+	case <-r.done:
+		select {
+		case res := <-reply:
+			return res, res.Err
+		default:
+			return nil, newError("房间不存在")
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *Room) Join(ctx context.Context, player, lang string) (*CommandResult, error) {
+	return r.join(ctx, &joinQuitMsg{player: player, lang: lang})
+}
+
+func (r *Room) join(ctx context.Context, m *joinQuitMsg) (*CommandResult, error) {
+	reply := make(chan *CommandResult, 1)
+	m.reply = reply
+	select {
+	case r.joinChan <- m:
+	case <-r.done:
+		return nil, newError("房间不存在")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return r.await(ctx, reply)
+}
+
+func (r *Room) Request(ctx context.Context, msg *ClientMessage) (*CommandResult, error) {
+	msg.reply = make(chan *CommandResult, 1)
+	select {
+	case r.cmdChan <- msg:
+	case <-r.done:
+		return nil, newError("房间不存在")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return r.await(ctx, msg.reply)
+}
+
+func (r *Room) Do(ctx context.Context, player, command string, args any) (*CommandResult, error) {
+	msg := &ClientMessage{Command: command, Player: player, Room: r.name}
+	if args != nil {
+		data, err := json.Marshal(args)
+		if err != nil {
+			return nil, err
+		}
+		msg.Data = data
+	}
+	return r.Request(ctx, msg)
+}
+
+func (r *Room) Attach(ctx context.Context, player, lang string) (*Conn, error) {
+	conn := newLocalConn("local:" + player)
+	reply := make(chan *CommandResult, 1)
+	select {
+	case r.joinChan <- &joinQuitMsg{player: player, lang: lang, conn: conn, reply: reply}:
+	case <-r.done:
+		return nil, newError("房间不存在")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if _, err := r.await(ctx, reply); err != nil {
+		r.Detach(player, conn)
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (r *Room) Detach(player string, conn *Conn) {
+	r.TryRemoveSubscribe(player, conn)
+	conn.Close()
+}
@@ -0,0 +1,228 @@
+package sim_board
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const apiTimeout = 10 * time.Second
+
+type APIError struct {
+	Error string          `json:"error"`
+	State json.RawMessage `json:"state,omitempty"`
+}
+
+type apiCreateRoomArgs struct {
+	Name   string `json:"name"`
+	Player string `json:"player"`
+	createRoomArgs
+}
+
+type APICommandResponse struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	State  json.RawMessage `json:"state"`
+}
+
+type APIRoomResponse struct {
+	Room    RoomSummary     `json:"room"`
+	State   json.RawMessage `json:"state"`
+	Session string          `json:"session,omitempty"`
+	Invite  *InviteResponse `json:"invite,omitempty"`
+}
+
+func apiJoinResponse(room *Room, res *CommandResult) *APIRoomResponse {
+	var joined JoinResult
+	_ = json.Unmarshal(res.Result, &joined)
+	return &APIRoomResponse{Room: room.Summary(), State: res.State, Session: joined.Session}
+}
+
+func apiLang(c *gin.Context) string {
+	if lang, ok := NormalizeLang(c.Query("lang")); ok {
+		return lang
+	}
+	return NegotiateLang(c.GetHeader("Accept-Language"))
+}
+
+func apiPlayer(c *gin.Context) string {
+	if player := c.GetHeader("X-Player"); player != "" {
+		return player
+	}
+	return c.Query("player")
+}
+
+func apiError(c *gin.Context, status int, lang string, err error, state json.RawMessage) {
+	c.AbortWithStatusJSON(status, &APIError{Error: LocalizeError(err, lang), State: state})
+}
+
+func apiFail(c *gin.Context, lang string, res *CommandResult, err error) {
+	var ce *conflictError
+	switch {
+	case errors.As(err, &ce):
+		apiError(c, http.StatusConflict, lang, err, res.State)
+	case errors.Is(err, errInvalidSession):
+		apiError(c, http.StatusForbidden, lang, err, nil)
+	case errors.Is(err, context.DeadlineExceeded):
+		apiError(c, http.StatusGatewayTimeout, lang, newError("请求超时"), nil)
+	default:
+		apiError(c, http.StatusBadRequest, lang, err, nil)
+	}
+}
+
+func apiRoom(c *gin.Context, lang, player string) (*Room, bool) {
+	if !apiLimiters.allow(c.ClientIP()) {
+		apiError(c, http.StatusTooManyRequests, lang, errThrottled, nil)
+		return nil, false
+	}
+	room, ok := GetRoom(c.Param("room"))
+	if !ok {
+		apiError(c, http.StatusNotFound, lang, newError("房间不存在"), nil)
+		return nil, false
+	}
+	if player == "" {
+		apiError(c, http.StatusBadRequest, lang, newError("房间名和玩家名不能为空"), nil)
+		return nil, false
+	}
+	err := room.authorizeFrom(c.ClientIP(), player, c.GetHeader("X-Room-Password"), c.GetHeader("X-Room-Invite"))
+	if errors.Is(err, errThrottled) {
+		apiError(c, http.StatusTooManyRequests, lang, err, nil)
+		return nil, false
+	}
+	if err != nil {
+		apiError(c, http.StatusForbidden, lang, err, nil)
+		return nil, false
+	}
+	return room, true
+}
+
+func initAPI(g *gin.RouterGroup) {
+	api := g.Group("/api/v1")
+	api.GET("/decks", func(c *gin.Context) {
+		lang := apiLang(c)
+		c.JSON(http.StatusOK, gin.H{
+			"decks":  GetAllAvailableDecks(lang),
+			"titles": GetDeckTitles(lang),
+		})
+	})
+	api.GET("/rooms", func(c *gin.Context) {
+		c.JSON(http.StatusOK, ListRooms())
+	})
+	api.GET("/presets", func(c *gin.Context) {
+		c.JSON(http.StatusOK, ListPresets(apiLang(c)))
+	})
+	api.POST("/rooms", apiCreateRoom)
+	api.GET("/rooms/:room", func(c *gin.Context) {
+		lang := apiLang(c)
+		player := apiPlayer(c)
+		room, ok := apiRoom(c, lang, player)
+		if !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), apiTimeout)
+		defer cancel()
+		res, err := room.Request(ctx, &ClientMessage{Command: "state", Player: player, Room: room.name, session: c.GetHeader("X-Session"), verify: true})
+		if err != nil {
+			apiFail(c, lang, res, err)
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", res.State)
+	})
+	api.POST("/rooms/:room/:cmd", apiCommand)
+}
+
+func apiCreateRoom(c *gin.Context) {
+	lang := apiLang(c)
+	if !apiLimiters.allow(c.ClientIP()) {
+		apiError(c, http.StatusTooManyRequests, lang, errThrottled, nil)
+		return
+	}
+	var args apiCreateRoomArgs
+	if err := c.ShouldBindJSON(&args); err != nil {
+		apiError(c, http.StatusBadRequest, lang, newError("请求格式错误"), nil)
+		return
+	}
+	if l, ok := NormalizeLang(args.Lang); ok {
+		lang = l
+	}
+	if args.Name == "" || args.Player == "" {
+		apiError(c, http.StatusBadRequest, lang, newError("房间名和玩家名不能为空"), nil)
+		return
+	}
+	room, err := CreateRoom(args.Name, args.Player, c.ClientIP(), args.RoomMeta, args.Password)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, lang, err, nil)
+		return
+	}
+	logrus.Infof("player %s from %s created room '%s' via api", args.Player, c.ClientIP(), args.Name)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), apiTimeout)
+	defer cancel()
+	res, err := room.Join(ctx, args.Player, lang)
+	if err != nil {
+		apiFail(c, lang, res, err)
+		return
+	}
+	resp := apiJoinResponse(room, res)
+	if args.CreateInvite {
+		token, expiresAt := room.auth.createInvite(room.name, defaultInviteTTL)
+		resp.Invite = &InviteResponse{Room: room.name, Token: token, ExpiresAt: expiresAt}
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+func apiCommand(c *gin.Context) {
+	lang := apiLang(c)
+	player := apiPlayer(c)
+	room, ok := apiRoom(c, lang, player)
+	if !ok {
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, config.MaxMessageSize))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, lang, newError("请求格式错误"), nil)
+		return
+	}
+	if len(data) > 0 && !json.Valid(data) {
+		apiError(c, http.StatusBadRequest, lang, newError("请求格式错误"), nil)
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), apiTimeout)
+	defer cancel()
+	cmd := c.Param("cmd")
+	if cmd == "join" {
+		var args joinArgs
+		if len(data) > 0 {
+			_ = json.Unmarshal(data, &args)
+		}
+		if l, ok := NormalizeLang(args.Lang); ok {
+			lang = l
+		}
+		res, err := room.join(ctx, &joinQuitMsg{player: player, lang: lang, session: c.GetHeader("X-Session"), verify: true})
+		if err != nil {
+			apiFail(c, lang, res, err)
+			return
+		}
+		c.JSON(http.StatusOK, apiJoinResponse(room, res))
+		return
+	}
+	msg := &ClientMessage{Command: cmd, Player: player, Room: room.name, Data: data, session: c.GetHeader("X-Session"), verify: true}
+	if expect := c.GetHeader("X-Expect"); expect != "" {
+		msg.Expect = new(Preconditions)
+		if err = json.Unmarshal([]byte(expect), msg.Expect); err != nil {
+			apiError(c, http.StatusBadRequest, lang, newError("请求格式错误"), nil)
+			return
+		}
+	}
+	logrus.Infof("recv api msg player=%s, room=%s, cmd=%s, data=%s", player, room.name, cmd, string(data))
+	res, err := room.Request(ctx, msg)
+	if err != nil {
+		apiFail(c, lang, res, err)
+		return
+	}
+	c.JSON(http.StatusOK, &APICommandResponse{Result: res.Result, State: res.State})
+}
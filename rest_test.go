@@ -0,0 +1,86 @@
+package sim_board
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func testAPI(t *testing.T, ip string) func(method, path, body string, header map[string]string) int {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	initAPI(e.Group(""))
+	return func(method, path, body string, header map[string]string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = ip + ":1234"
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w.Code
+	}
+}
+
+func TestAPIAuthFailuresThrottled(t *testing.T) {
+	r, err := CreateRoom(t.Name(), "host", t.Name(), RoomMeta{}, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { testCloseRoom(t, r) })
+	testJoin(t, r, "host")
+	authFailures.lock.Lock()
+	authFailures.limiters["198.51.100.1"] = newRateLimiter(1e-6, authFailureBurst)
+	authFailures.lock.Unlock()
+	do := testAPI(t, "198.51.100.1")
+	wrong := map[string]string{"X-Player": "mallory", "X-Room-Password": "guess"}
+	for i := 0; i < authFailureBurst; i++ {
+		if code := do("GET", "/api/v1/rooms/"+r.name, "", wrong); code != http.StatusForbidden {
+			t.Fatalf("attempt %d: status %d, want 403", i, code)
+		}
+	}
+	if code := do("GET", "/api/v1/rooms/"+r.name, "", wrong); code != http.StatusTooManyRequests {
+		t.Fatalf("status %d after repeated failures, want 429", code)
+	}
+	right := map[string]string{"X-Player": "mallory", "X-Room-Password": "secret"}
+	if code := do("POST", "/api/v1/rooms/"+r.name+"/join", "", right); code != http.StatusTooManyRequests {
+		t.Fatalf("status %d for a correct password while throttled, want 429", code)
+	}
+	if code := testAPI(t, "198.51.100.2")("POST", "/api/v1/rooms/"+r.name+"/join", "", right); code != http.StatusOK {
+		t.Fatalf("status %d from another address, want 200", code)
+	}
+}
+
+func TestAPIRateLimited(t *testing.T) {
+	prev := *config
+	defer func() { *config = prev }()
+	config.RateLimit = 1
+	config.RateBurst = 3
+	do := testAPI(t, "198.51.100.3")
+	tests := []struct {
+		method, path string
+	}{
+		{"GET", "/api/v1/rooms/missing"},
+		{"POST", "/api/v1/rooms/missing/state"},
+		{"POST", "/api/v1/rooms/missing/join"},
+		{"POST", "/api/v1/rooms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			apiLimiters.lock.Lock()
+			delete(apiLimiters.limiters, "198.51.100.3")
+			apiLimiters.lock.Unlock()
+			for i := 0; i < config.RateBurst; i++ {
+				if code := do(tt.method, tt.path, "", map[string]string{"X-Player": "p"}); code == http.StatusTooManyRequests {
+					t.Fatalf("request %d throttled within burst", i)
+				}
+			}
+			if code := do(tt.method, tt.path, "", map[string]string{"X-Player": "p"}); code != http.StatusTooManyRequests {
+				t.Fatalf("status %d beyond burst, want 429", code)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package sim_board
+
+import (
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxRollHistory = 20
+	maxRollDice    = 100
+	maxRollSides   = 1000
+	maxRollTerms   = 20
+)
+
+type DiceDeck interface {
+	Roll() Card
+	Value(card Card) (int, bool)
+}
+
+type RollTerm struct {
+	Expr  string `json:"expr"`
+	Rolls []int  `json:"rolls,omitempty"`
+	Kept  []int  `json:"kept,omitempty"`
+	Sum   int    `json:"sum"`
+}
+
+type RollRecord struct {
+	Player   string     `json:"player"`
+	Notation string     `json:"notation,omitempty"`
+	Terms    []RollTerm `json:"terms,omitempty"`
+	Deck     *int       `json:"deck,omitempty"`
+	ID       string     `json:"id,omitempty"`
+	Faces    []Card     `json:"faces,omitempty"`
+	Total    int        `json:"total"`
+	Time     time.Time  `json:"time"`
+}
+
+var rollTermPattern = regexp.MustCompile(`^([+-]?)(?:(\d*)d(\d+|f|%)(?:(kh|kl|dh|dl)(\d+))?|(\d+))`)
+
+func rollDie(sides string) int {
+	switch sides {
+	case "f":
+		return rand.Intn(3) - 1
+	case "%":
+		return rand.Intn(100) + 1
+	}
+	n, _ := strconv.Atoi(sides)
+	return rand.Intn(n) + 1
+}
+
+func RollNotation(notation string) ([]RollTerm, int, error) {
+	fields := strings.Fields(strings.ToLower(notation))
+	for i := 1; i < len(fields); i++ {
+		if !strings.HasSuffix(fields[i-1], "+") && !strings.HasSuffix(fields[i-1], "-") &&
+			!strings.HasPrefix(fields[i], "+") && !strings.HasPrefix(fields[i], "-") {
+			return nil, 0, newError("骰子表达式无效：%s", notation)
+		}
+	}
+	s := strings.Join(fields, "")
+	if s == "" {
+		return nil, 0, newError("骰子表达式无效：%s", notation)
+	}
+	var terms []RollTerm
+	total := 0
+	for s != "" {
+		m := rollTermPattern.FindStringSubmatch(s)
+		if m == nil || (len(terms) > 0 && m[1] == "") || len(terms) >= maxRollTerms {
+			return nil, 0, newError("骰子表达式无效：%s", notation)
+		}
+		s = s[len(m[0]):]
+		sign := 1
+		if m[1] == "-" {
+			sign = -1
+		}
+		term := RollTerm{Expr: m[0]}
+		if m[6] != "" {
+			n, err := strconv.Atoi(m[6])
+			if err != nil {
+				return nil, 0, newError("骰子表达式无效：%s", notation)
+			}
+			term.Sum = n
+		} else {
+			count := 1
+			if m[2] != "" {
+				count, _ = strconv.Atoi(m[2])
+			}
+			if sides, err := strconv.Atoi(m[3]); err == nil && (sides < 1 || sides > maxRollSides) {
+				return nil, 0, newError("骰子表达式无效：%s", notation)
+			}
+			if count < 1 || count > maxRollDice {
+				return nil, 0, newError("骰子表达式无效：%s", notation)
+			}
+			for i := 0; i < count; i++ {
+				term.Rolls = append(term.Rolls, rollDie(m[3]))
+			}
+			term.Kept = append([]int(nil), term.Rolls...)
+			if m[4] != "" {
+				k, _ := strconv.Atoi(m[5])
+				if k > count {
+					return nil, 0, newError("骰子表达式无效：%s", notation)
+				}
+				sort.Ints(term.Kept)
+				switch m[4] {
+				case "kh":
+					term.Kept = term.Kept[count-k:]
+				case "kl":
+					term.Kept = term.Kept[:k]
+				case "dh":
+					term.Kept = term.Kept[:count-k]
+				case "dl":
+					term.Kept = term.Kept[k:]
+				}
+			}
+			for _, v := range term.Kept {
+				term.Sum += v
+			}
+		}
+		term.Sum *= sign
+		total += term.Sum
+		terms = append(terms, term)
+	}
+	return terms, total, nil
+}
+
+func (r *Room) recordRoll(rec *RollRecord) {
+	rec.Time = time.Now()
+	r.rolls = append(r.rolls, rec)
+	if len(r.rolls) > maxRollHistory {
+		r.rolls = r.rolls[len(r.rolls)-maxRollHistory:]
+	}
+	event := &ServerMessage{Type: "roll", Data: rec}
+	for p := range r.hole {
+		r.sendMsgTo(p, event)
+	}
+}
+
+func diceTotal(dd DiceDeck, faces []Card) int {
+	total := 0
+	for _, face := range faces {
+		if v, ok := dd.Value(face); ok {
+			total += v
+		}
+	}
+	return total
+}
+
+type RollArgs struct {
+	Notation string `json:"notation"`
+}
+
+func (r *Room) handleRoll(player string, args RollArgs) (any, error) {
+	terms, total, err := RollNotation(args.Notation)
+	if err != nil {
+		return nil, err
+	}
+	rec := &RollRecord{Player: player, Notation: args.Notation, Terms: terms, Total: total}
+	r.recordRoll(rec)
+	r.broadcast()
+	return rec, nil
+}
+
+func (r *Room) handleReroll(player string, args CollectArgs) (any, error) {
+	card, err := r.boardCard(player, args.ID, args.OpID)
+	if err != nil {
+		return nil, err
+	}
+	d, ok := r.deck(card.Card.DeckId)
+	if !ok {
+		return nil, newError("牌堆不存在")
+	}
+	dd, ok := d.Deck.(DiceDeck)
+	if !ok {
+		return nil, newError("该牌堆不是骰子")
+	}
+	card.Card.Card = dd.Roll()
+	card.OpID++
+	faces := []Card{card.Card.Card}
+	id := d.id
+	rec := &RollRecord{Player: player, Deck: &id, ID: args.ID, Faces: faces, Total: diceTotal(dd, faces)}
+	z, ok := r.zone(card.Zone)
+	if !ok || z.Visibility == ZonePublic {
+		r.recordRoll(rec)
+	}
+	r.broadcast()
+	if ok && !z.visibleTo(player) {
+		return &RollRecord{Player: player, Deck: &id, ID: args.ID}, nil
+	}
+	return rec, nil
+}
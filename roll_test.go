@@ -0,0 +1,70 @@
+package sim_board
+
+import "testing"
+
+func TestRollNotation(t *testing.T) {
+	tests := []struct {
+		notation string
+		terms    int
+		kept     []int
+		min, max int
+		wantErr  bool
+	}{
+		{notation: "d6", terms: 1, kept: []int{1}, min: 1, max: 6},
+		{notation: "2d6+1", terms: 2, kept: []int{2, 0}, min: 3, max: 13},
+		{notation: "2d6 + 3", terms: 2, kept: []int{2, 0}, min: 5, max: 15},
+		{notation: "1d20-2", terms: 2, kept: []int{1, 0}, min: -1, max: 18},
+		{notation: "4d6kh3", terms: 1, kept: []int{3}, min: 3, max: 18},
+		{notation: "4d6kl1", terms: 1, kept: []int{1}, min: 1, max: 6},
+		{notation: "4d6dh1", terms: 1, kept: []int{3}, min: 3, max: 18},
+		{notation: "4d6dl3", terms: 1, kept: []int{1}, min: 1, max: 6},
+		{notation: "4dF", terms: 1, kept: []int{4}, min: -4, max: 4},
+		{notation: "d%", terms: 1, kept: []int{1}, min: 1, max: 100},
+		{notation: "D8", terms: 1, kept: []int{1}, min: 1, max: 8},
+		{notation: "5", terms: 1, kept: []int{0}, min: 5, max: 5},
+		{notation: "-d4", terms: 1, kept: []int{1}, min: -4, max: -1},
+		{notation: "", wantErr: true},
+		{notation: "   ", wantErr: true},
+		{notation: "1d6 2", wantErr: true},
+		{notation: "2d6++1", wantErr: true},
+		{notation: "0d6", wantErr: true},
+		{notation: "d0", wantErr: true},
+		{notation: "101d6", wantErr: true},
+		{notation: "d1001", wantErr: true},
+		{notation: "3d6kh4", wantErr: true},
+		{notation: "2d6x", wantErr: true},
+		{notation: "1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.notation, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				terms, total, err := RollNotation(tt.notation)
+				if tt.wantErr {
+					if err == nil {
+						t.Fatalf("expected error, got %d", total)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(terms) != tt.terms {
+					t.Fatalf("got %d terms, want %d", len(terms), tt.terms)
+				}
+				sum := 0
+				for j, term := range terms {
+					if len(term.Kept) != tt.kept[j] {
+						t.Fatalf("term %d kept %d dice, want %d", j, len(term.Kept), tt.kept[j])
+					}
+					sum += term.Sum
+				}
+				if sum != total {
+					t.Fatalf("total %d does not match term sums %d", total, sum)
+				}
+				if total < tt.min || total > tt.max {
+					t.Fatalf("total %d out of range [%d, %d]", total, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,552 @@
+package sim_board
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"runtime/metrics"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const (
+	maxScriptActions = 256
+	scriptStackSize  = 256
+	scriptRegistry   = 64 * 1024
+	maxScriptString  = 1 << 20
+	scriptMemoryTick = time.Millisecond
+)
+
+var errScriptMemory = errors.New("script exceeded its memory limit")
+
+type roomScript struct {
+	L       *lua.LState
+	hooks   map[string][]*lua.LFunction
+	hands   []string
+	actions int
+	running bool
+}
+
+type ScriptErrorResponse struct {
+	Event   string `json:"event"`
+	Message string `json:"message"`
+}
+
+func newScriptState() *lua.LState {
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:        true,
+		CallStackSize:       scriptStackSize,
+		RegistrySize:        scriptRegistry / 4,
+		RegistryMaxSize:     scriptRegistry,
+		IncludeGoStackTrace: false,
+	})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "require", "module", "collectgarbage", "getfenv", "setfenv"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	str := L.GetGlobal(lua.StringLibName).(*lua.LTable)
+	str.RawSetString("rep", L.NewFunction(scriptStringRep))
+	tab := L.GetGlobal(lua.TabLibName).(*lua.LTable)
+	concat := tab.RawGetString("concat").(*lua.LFunction).GFunction
+	tab.RawSetString("concat", L.NewFunction(func(L *lua.LState) int {
+		if scriptConcatLen(L) > maxScriptString {
+			L.RaiseError("resulting string too large")
+		}
+		return concat(L)
+	}))
+	format := str.RawGetString("format").(*lua.LFunction).GFunction
+	str.RawSetString("format", L.NewFunction(func(L *lua.LState) int {
+		if !scriptFormatOK(L.CheckString(1)) {
+			L.RaiseError("invalid format (width or precision too long)")
+		}
+		return format(L)
+	}))
+	return L
+}
+
+func scriptStringRep(L *lua.LState) int {
+	s := L.CheckString(1)
+	n := L.CheckInt(2)
+	if n <= 0 || s == "" {
+		L.Push(lua.LString(""))
+		return 1
+	}
+	if n > maxScriptString/len(s) {
+		L.RaiseError("resulting string too large")
+	}
+	L.Push(lua.LString(strings.Repeat(s, n)))
+	return 1
+}
+
+func scriptConcatLen(L *lua.LState) int {
+	tbl := L.CheckTable(1)
+	sep := L.OptString(2, "")
+	i := L.OptInt(3, 1)
+	j := L.OptInt(4, tbl.Len())
+	n := 0
+	for k := i; k <= j && n <= maxScriptString; k++ {
+		if s, ok := tbl.RawGetInt(k).(lua.LString); ok {
+			n += len(s)
+		} else {
+			n += 24
+		}
+		if k < j {
+			n += len(sep)
+		}
+	}
+	return n
+}
+
+func scriptFormatOK(format string) bool {
+	digits := func(i int) int {
+		j := i
+		for j < len(format) && format[j] >= '0' && format[j] <= '9' {
+			j++
+		}
+		return j
+	}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		if i < len(format) && format[i] == '%' {
+			continue
+		}
+		for i < len(format) && strings.IndexByte("-+ #0", format[i]) >= 0 {
+			i++
+		}
+		j := digits(i)
+		if j-i > 2 {
+			return false
+		}
+		i = j
+		if i < len(format) && format[i] == '.' {
+			j = digits(i + 1)
+			if j-i-1 > 2 {
+				return false
+			}
+			i = j
+		}
+	}
+	return true
+}
+
+func (r *Room) loadScript(source string) (*roomScript, error) {
+	s := &roomScript{L: newScriptState(), hooks: make(map[string][]*lua.LFunction)}
+	r.openScriptAPI(s)
+	fn, err := s.L.LoadString(source)
+	if err != nil {
+		s.L.Close()
+		return nil, newError("脚本错误：%v", err.Error())
+	}
+	prev := r.script
+	r.script = s
+	err = r.runScript(func() error {
+		s.L.Push(fn)
+		return s.L.PCall(0, 0, nil)
+	})
+	r.script = prev
+	if err != nil {
+		s.L.Close()
+		return nil, newError("脚本错误：%v", err.Error())
+	}
+	return s, nil
+}
+
+func (r *Room) runScript(f func() error) error {
+	s := r.script
+	parent, abort := context.WithCancelCause(context.Background())
+	defer abort(nil)
+	ctx, cancel := context.WithTimeout(parent, time.Duration(config.ScriptTimeout))
+	defer cancel()
+	go watchScriptMemory(ctx, abort, uint64(config.ScriptMemory))
+	s.L.SetContext(ctx)
+	defer s.L.RemoveContext()
+	s.running = true
+	s.actions = 0
+	defer func() {
+		s.running = false
+	}()
+	err := f()
+	if err != nil && context.Cause(parent) == errScriptMemory {
+		return errScriptMemory
+	}
+	return err
+}
+
+func watchScriptMemory(ctx context.Context, abort context.CancelCauseFunc, limit uint64) {
+	sample := []metrics.Sample{{Name: "/gc/heap/allocs:bytes"}}
+	metrics.Read(sample)
+	base := sample[0].Value.Uint64()
+	ticker := time.NewTicker(scriptMemoryTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			metrics.Read(sample)
+			if sample[0].Value.Uint64()-base > limit {
+				abort(errScriptMemory)
+				return
+			}
+		}
+	}
+}
+
+func (r *Room) fireScript(event string, args ...any) {
+	s := r.script
+	if s == nil || s.running || r.host == "" || len(s.hooks[event]) == 0 {
+		return
+	}
+	values := make([]lua.LValue, 0, len(args))
+	for _, arg := range args {
+		values = append(values, toLua(s.L, arg))
+	}
+	err := r.runScript(func() error {
+		for _, fn := range s.hooks[event] {
+			if err := s.L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, values...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.Warnf("script of room '%s' failed on %s: %v", r.name, event, err)
+		r.sendMsgTo(r.host, &ServerMessage{Type: "script_error", Data: &ScriptErrorResponse{Event: event, Message: err.Error()}})
+	}
+}
+
+func (r *Room) scriptTouchHand(player string) {
+	if r.script != nil && !r.script.running && !SliceContains(r.script.hands, player) {
+		r.script.hands = append(r.script.hands, player)
+	}
+}
+
+func (r *Room) fireHandHooks() {
+	if r.script == nil {
+		return
+	}
+	hands := r.script.hands
+	r.script.hands = nil
+	for _, player := range hands {
+		if _, ok := r.hole[player]; ok {
+			r.fireScript("hand", player, r.handSize(player))
+		}
+	}
+}
+
+func (r *Room) fireCommandHooks(msg *ClientMessage, err error) {
+	if r.script == nil {
+		return
+	}
+	switch msg.Command {
+	case "nop", "state", "set_script", "get_script":
+		r.script.hands = nil
+		return
+	}
+	if err != nil {
+		r.script.hands = nil
+		return
+	}
+	var data any
+	_ = json.Unmarshal(msg.Data, &data)
+	r.fireScript(msg.Command, msg.Player, data)
+	r.fireHandHooks()
+}
+
+func (r *Room) handSize(player string) int {
+	n := 0
+	for _, cnt := range r.hole[player] {
+		n += cnt
+	}
+	return n
+}
+
+func toLua(L *lua.LState, v any) lua.LValue {
+	switch v := v.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(v)
+	case int:
+		return lua.LNumber(v)
+	case float64:
+		return lua.LNumber(v)
+	case string:
+		return lua.LString(v)
+	case []any:
+		t := L.CreateTable(len(v), 0)
+		for _, e := range v {
+			t.Append(toLua(L, e))
+		}
+		return t
+	case map[string]any:
+		t := L.CreateTable(0, len(v))
+		for k, e := range v {
+			t.RawSetString(k, toLua(L, e))
+		}
+		return t
+	}
+	return lua.LNil
+}
+
+func (r *Room) scriptAction(L *lua.LState) {
+	r.script.actions++
+	if r.script.actions > maxScriptActions {
+		L.RaiseError("too many actions")
+	}
+}
+
+func scriptResult(L *lua.LState, err error) int {
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
+func (r *Room) openScriptAPI(s *roomScript) {
+	L := s.L
+	L.SetGlobal("print", L.NewFunction(func(L *lua.LState) int {
+		parts := make([]string, 0, L.GetTop())
+		for i := 1; i <= L.GetTop(); i++ {
+			parts = append(parts, L.ToStringMeta(L.Get(i)).String())
+		}
+		logrus.Infof("script of room '%s': %s", r.name, strings.Join(parts, " "))
+		return 0
+	}))
+	L.SetGlobal("on", L.NewFunction(func(L *lua.LState) int {
+		event := L.CheckString(1)
+		fn := L.CheckFunction(2)
+		s.hooks[event] = append(s.hooks[event], fn)
+		return 0
+	}))
+	room := L.NewTable()
+	L.SetFuncs(room, map[string]lua.LGFunction{
+		"host": func(L *lua.LState) int {
+			L.Push(lua.LString(r.host))
+			return 1
+		},
+		"players": func(L *lua.LState) int {
+			t := L.CreateTable(len(r.players), 0)
+			for _, p := range r.players {
+				t.Append(lua.LString(p))
+			}
+			L.Push(t)
+			return 1
+		},
+		"decks": func(L *lua.LState) int {
+			t := L.CreateTable(len(r.decks), 0)
+			for _, d := range r.decks {
+				e := L.CreateTable(0, 5)
+				e.RawSetString("id", lua.LNumber(d.id))
+				e.RawSetString("type", lua.LString(d.Type()))
+				e.RawSetString("name", lua.LString(d.Name()))
+				e.RawSetString("rest", lua.LNumber(d.RestLen()))
+				e.RawSetString("max", lua.LNumber(d.MaxLen()))
+				t.Append(e)
+			}
+			L.Push(t)
+			return 1
+		},
+		"board": func(L *lua.LState) int {
+			t := L.CreateTable(0, len(r.board))
+			for id, card := range r.board {
+				c, _ := card.Card.MarshalText()
+				e := L.CreateTable(0, 8)
+				e.RawSetString("card", lua.LString(c))
+				e.RawSetString("deck", lua.LNumber(card.Card.DeckId))
+				e.RawSetString("x", lua.LNumber(card.X))
+				e.RawSetString("y", lua.LNumber(card.Y))
+				e.RawSetString("op_id", lua.LNumber(card.OpID))
+				e.RawSetString("z", lua.LNumber(card.Z))
+				e.RawSetString("rotation", lua.LNumber(card.Rotation))
+				e.RawSetString("zone", lua.LString(card.Zone))
+				e.RawSetString("owner", lua.LString(card.Owner))
+				t.RawSetString(id, e)
+			}
+			L.Push(t)
+			return 1
+		},
+		"hand": func(L *lua.LState) int {
+			hole := r.hole[L.CheckString(1)]
+			t := L.CreateTable(0, len(hole))
+			for card, cnt := range hole {
+				c, _ := card.MarshalText()
+				t.RawSetString(string(c), lua.LNumber(cnt))
+			}
+			L.Push(t)
+			return 1
+		},
+		"hand_size": func(L *lua.LState) int {
+			L.Push(lua.LNumber(r.handSize(L.CheckString(1))))
+			return 1
+		},
+		"say": func(L *lua.LState) int {
+			r.scriptAction(L)
+			msg := &ServerMessage{Type: "script", Data: L.CheckString(1)}
+			if player := L.OptString(2, ""); player != "" {
+				r.sendMsgTo(player, msg)
+			} else {
+				for p := range r.hole {
+					r.sendMsgTo(p, msg)
+				}
+			}
+			return 0
+		},
+		"draw": func(L *lua.LState) int {
+			r.scriptAction(L)
+			_, err := r.handleDraw(r.host, DrawArgs{Deck: L.CheckInt(1), Num: L.CheckInt(2), Target: L.OptString(3, ""), Zone: L.OptString(4, "")})
+			return scriptResult(L, err)
+		},
+		"deal": func(L *lua.LState) int {
+			r.scriptAction(L)
+			args := DealArgs{Deck: L.CheckInt(1), Num: L.OptInt(2, 0), All: L.OptInt(2, 0) == 0}
+			if t, ok := L.Get(3).(*lua.LTable); ok {
+				t.ForEach(func(_, v lua.LValue) {
+					args.Players = append(args.Players, v.String())
+				})
+			}
+			_, err := r.handleDeal(r.host, args)
+			return scriptResult(L, err)
+		},
+		"roll": func(L *lua.LState) int {
+			r.scriptAction(L)
+			ret, err := r.handleRoll(r.host, RollArgs{Notation: L.CheckString(1)})
+			if err != nil {
+				return scriptResult(L, err)
+			}
+			L.Push(lua.LNumber(ret.(*RollRecord).Total))
+			return 1
+		},
+		"announce": func(L *lua.LState) int {
+			r.scriptAction(L)
+			var card DeckCard
+			if err := card.UnmarshalText([]byte(L.CheckString(2))); err != nil {
+				return scriptResult(L, err)
+			}
+			x, y := getRandomPos(nil)
+			_, err := r.handleAnnounce(L.CheckString(1), AnnounceArgs{
+				DeckCard: card,
+				X:        float32(L.OptNumber(3, lua.LNumber(x))),
+				Y:        float32(L.OptNumber(4, lua.LNumber(y))),
+			})
+			return scriptResult(L, err)
+		},
+		"move": func(L *lua.LState) int {
+			r.scriptAction(L)
+			id := L.CheckString(1)
+			card, ok := r.board[id]
+			if !ok {
+				return scriptResult(L, newError("公共牌不存在"))
+			}
+			_, err := r.handleMove(r.host, MoveArgs{ID: id, OpID: card.OpID, X: float32(L.CheckNumber(2)), Y: float32(L.CheckNumber(3))})
+			return scriptResult(L, err)
+		},
+		"rotate": func(L *lua.LState) int {
+			r.scriptAction(L)
+			id := L.CheckString(1)
+			card, ok := r.board[id]
+			if !ok {
+				return scriptResult(L, newError("公共牌不存在"))
+			}
+			_, err := r.handleRotate(r.host, RotateArgs{ID: id, OpID: card.OpID, Rotation: L.CheckInt(2)})
+			return scriptResult(L, err)
+		},
+		"collect": func(L *lua.LState) int {
+			r.scriptAction(L)
+			player, id := L.CheckString(1), L.CheckString(2)
+			card, ok := r.board[id]
+			if !ok {
+				return scriptResult(L, newError("公共牌不存在"))
+			}
+			if _, ok = r.hole[player]; !ok {
+				return scriptResult(L, newError("目标不存在"))
+			}
+			_, err := r.handleCollect(player, CollectArgs{ID: id, OpID: card.OpID})
+			return scriptResult(L, err)
+		},
+		"discard": func(L *lua.LState) int {
+			r.scriptAction(L)
+			id := L.CheckString(1)
+			card, ok := r.board[id]
+			if !ok {
+				return scriptResult(L, newError("公共牌不存在"))
+			}
+			_, err := r.handleDiscardBoard(r.host, CollectArgs{ID: id, OpID: card.OpID})
+			return scriptResult(L, err)
+		},
+		"reset": func(L *lua.LState) int {
+			r.scriptAction(L)
+			args := RecallArgs{Scope: RecallScope(L.OptString(1, string(RecallAll)))}
+			switch args.Scope {
+			case RecallDeck:
+				args.Deck = L.CheckInt(2)
+			case RecallPlayer:
+				args.Player = L.CheckString(2)
+			}
+			_, err := r.handleRecall(r.host, args)
+			return scriptResult(L, err)
+		},
+	})
+	L.SetGlobal("room", room)
+}
+
+type SetScriptArgs struct {
+	Source string `json:"source"`
+}
+
+func (r *Room) handleSetScript(player string, args SetScriptArgs) (any, error) {
+	if err := r.requireHost(player); err != nil {
+		return nil, err
+	}
+	if len(args.Source) > config.MaxScriptSize {
+		return nil, newError("脚本过大")
+	}
+	if strings.TrimSpace(args.Source) == "" {
+		r.closeScript()
+		return nil, nil
+	}
+	s, err := r.loadScript(args.Source)
+	if err != nil {
+		return nil, err
+	}
+	r.closeScript()
+	r.script = s
+	r.scriptSource = args.Source
+	return nil, nil
+}
+
+func (r *Room) handleGetScript(player string) (any, error) {
+	if err := r.requireHost(player); err != nil {
+		return nil, err
+	}
+	return &SetScriptArgs{Source: r.scriptSource}, nil
+}
+
+func (r *Room) closeScript() {
+	if r.script != nil {
+		r.script.L.Close()
+		r.script = nil
+	}
+	r.scriptSource = ""
+}
@@ -0,0 +1,51 @@
+package sim_board
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScriptLimits(t *testing.T) {
+	prev := *config
+	defer func() { *config = prev }()
+	config.ScriptTimeout = Duration(30 * time.Second)
+	tests := []struct {
+		name   string
+		source string
+		err    string
+	}{
+		{"concat doubling", `local s = "x" for i = 1, 40 do s = s .. s end`, errScriptMemory.Error()},
+		{"table growth", `local t = {} for i = 1, 1e9 do t[i] = i end`, errScriptMemory.Error()},
+		{"string.rep", `string.rep("x", 4e9)`, "resulting string too large"},
+		{"table.concat", `local t = {} for i = 1, 64 do t[i] = string.rep("x", 1e6) end table.concat(t)`, "resulting string too large"},
+		{"format width", `string.format("%999999999d", 1)`, "width or precision too long"},
+		{"within limits", `local s = string.rep("ab", 10) .. table.concat({"a", "b"}, ",") .. string.format("%5.2f", 1)`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			start := time.Now()
+			s, err := (&Room{}).loadScript(tt.source)
+			runtime.ReadMemStats(&after)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				s.L.Close()
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error = %v, want %q", err, tt.err)
+			}
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Errorf("script ran for %v before being stopped", elapsed)
+			}
+			if allocated := after.TotalAlloc - before.TotalAlloc; allocated > uint64(8*config.ScriptMemory) {
+				t.Errorf("script allocated %d bytes before being stopped", allocated)
+			}
+		})
+	}
+}
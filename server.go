@@ -2,12 +2,12 @@ package sim_board
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,71 +17,168 @@ import (
 
 type ClientMessage struct {
 	ID      uint64          `json:"-"`
+	ReqID   string          `json:"req_id"`
 	Command string          `json:"cmd"`
 	Player  string          `json:"player"`
 	Room    string          `json:"room"`
 	Data    json.RawMessage `json:"data"`
+	Expect  *Preconditions  `json:"expect"`
+	conn    *Conn
+	reply   chan *CommandResult
+	session string
+	verify  bool
 }
 
 type ServerMessage struct {
-	Type string `json:"type"`
-	Data any    `json:"data"`
+	Type  string `json:"type"`
+	ReqID string `json:"req_id,omitempty"`
+	Data  any    `json:"data"`
 }
 
-var roomMap sync.Map
+var (
+	roomMap   sync.Map
+	roomCount atomic.Int64
+)
 
 type joinQuitMsg struct {
-	player string
-	conn   *websocket.Conn
+	player  string
+	lang    string
+	reqID   string
+	session string
+	verify  bool
+	conn    *Conn
+	reply   chan *CommandResult
+}
+
+type JoinResult struct {
+	Session string `json:"session,omitempty"`
 }
 
 type Room struct {
+	name       string
+	creatorIP  string
+	meta       RoomMeta
+	host       string
+	createdAt  time.Time
+	summary    atomic.Pointer[RoomSummary]
+	auth       roomAuth
 	quitChan   chan *joinQuitMsg
 	joinChan   chan *joinQuitMsg
 	cmdChan    chan *ClientMessage
-	conn       map[string][]*websocket.Conn
+	conn       map[string][]*Conn
+	lang       map[string]string
+	presence   map[string]*Presence
+	done       chan struct{}
 	board      map[string]*PublicCard
 	hole       map[string]map[DeckCard]int
-	decks      []Deck
+	pot        map[DeckCard]int
+	rolls      []*RollRecord
+	decks      []*roomDeck
+	zones      []*Zone
 	players    []string
 	placeCnter uint
+
+	expiresAt    time.Time
+	expiryWarned bool
+	closeReason  string
+
+	version      uint64
+	handVersions map[string]uint64
+	nextDeckID   int
+	script       *roomScript
+	scriptSource string
 }
 
-func GetOrCreateRoom(name string) *Room {
-	r, ok := roomMap.LoadOrStore(name, &Room{})
-	room := r.(*Room)
-	if !ok {
-		room.cmdChan = make(chan *ClientMessage, 64)
-		room.joinChan = make(chan *joinQuitMsg, 8)
-		room.quitChan = make(chan *joinQuitMsg, 8)
-		room.conn = make(map[string][]*websocket.Conn)
-		room.board = make(map[string]*PublicCard)
-		room.hole = make(map[string]map[DeckCard]int)
-		go room.handleCommand(name)
-	}
-	return room
+func CreateRoom(name, host, ip string, meta RoomMeta, password string) (*Room, error) {
+	if meta.Title == "" {
+		meta.Title = name
+	}
+	if meta.MaxPlayers == 0 {
+		meta.MaxPlayers = config.MaxPlayers
+	}
+	if meta.MaxPlayers < 0 || (config.MaxPlayers > 0 && meta.MaxPlayers > config.MaxPlayers) {
+		return nil, newError("人数上限无效")
+	}
+	if meta.TTL < 0 {
+		return nil, newError("过期时间无效")
+	}
+	room := &Room{}
+	if err := room.auth.setPassword(password); err != nil {
+		return nil, newError("设置密码失败：%v", err.Error())
+	}
+	room.name = name
+	room.creatorIP = ip
+	room.meta = meta
+	room.host = host
+	room.createdAt = time.Now()
+	room.auth.inviteSecret = newInviteSecret()
+	room.done = make(chan struct{})
+	room.cmdChan = make(chan *ClientMessage, config.CmdBufferSize)
+	room.joinChan = make(chan *joinQuitMsg, config.JoinBufferSize)
+	room.quitChan = make(chan *joinQuitMsg, config.JoinBufferSize)
+	room.conn = make(map[string][]*Conn)
+	room.lang = make(map[string]string)
+	room.presence = make(map[string]*Presence)
+	room.board = make(map[string]*PublicCard)
+	room.hole = make(map[string]map[DeckCard]int)
+	room.pot = make(map[DeckCard]int)
+	room.handVersions = make(map[string]uint64)
+	room.publishSummary()
+	if config.MaxRooms > 0 && roomCount.Load() >= int64(config.MaxRooms) {
+		return nil, newError("房间数量已达上限")
+	}
+	if !quota.acquireRoom(ip) {
+		return nil, newError("创建的房间过多")
+	}
+	if _, ok := roomMap.LoadOrStore(name, room); ok {
+		quota.releaseRoom(ip)
+		return nil, newError("房间已存在")
+	}
+	roomCount.Add(1)
+	go room.handleCommand(name)
+	return room, nil
 }
 
 func GetRoom(name string) (*Room, bool) {
 	r, ok := roomMap.Load(name)
-	return r.(*Room), ok
+	if !ok {
+		return nil, false
+	}
+	return r.(*Room), true
 }
 
 func RemoveRoom(name string) bool {
-	_, ok := roomMap.LoadAndDelete(name)
+	r, ok := roomMap.LoadAndDelete(name)
+	if ok {
+		roomCount.Add(-1)
+		quota.releaseRoom(r.(*Room).creatorIP)
+	}
 	return ok
 }
 
-func (r *Room) TryRemoveSubscribe(player string, conn *websocket.Conn) {
-	r.quitChan <- &joinQuitMsg{player, conn}
+func (r *Room) TryRemoveSubscribe(player string, conn *Conn) {
+	select {
+	case r.quitChan <- &joinQuitMsg{player: player, conn: conn}:
+	case <-r.done:
+	}
 }
 
-func (r *Room) PushSubscribe(player string, conn *websocket.Conn) {
-	r.joinChan <- &joinQuitMsg{player, conn}
+func (r *Room) PushSubscribe(player, lang, reqID, session string, conn *Conn) bool {
+	select {
+	case r.joinChan <- &joinQuitMsg{player: player, lang: lang, reqID: reqID, session: session, verify: true, conn: conn}:
+		return true
+	case <-r.done:
+		return false
+	}
 }
 
-func (r *Room) PushRequest(msg *ClientMessage) {
-	r.cmdChan <- msg
+func (r *Room) PushRequest(msg *ClientMessage) bool {
+	select {
+	case r.cmdChan <- msg:
+		return true
+	case <-r.done:
+		return false
+	}
 }
 
 func (r *Room) handleQuit(m *joinQuitMsg) {
@@ -91,39 +188,191 @@ func (r *Room) handleQuit(m *joinQuitMsg) {
 	}
 	for i, conn := range arr {
 		if m.conn == conn {
-			arr = append(arr[:i], arr[i+1:]...)
+			r.conn[m.player] = append(arr[:i], arr[i+1:]...)
+			conn.detach(false)
 			break
 		}
 	}
+	if len(r.conn[m.player]) == 0 {
+		delete(r.conn, m.player)
+		r.setPresence(m.player, PresenceReconnecting)
+	} else {
+		r.presence[m.player].Conns = len(r.conn[m.player])
+	}
+}
+
+func (m *joinQuitMsg) fail(err error) {
+	if m.reply != nil {
+		m.reply <- &CommandResult{Err: err}
+	}
+	if m.conn != nil {
+		writeError(m.conn, m.lang, m.reqID, "%s", LocalizeError(err, m.lang))
+	}
 }
 
 func (r *Room) handleJoin(m *joinQuitMsg) {
-	r.conn[m.player] = append(r.conn[m.player], m.conn)
-	if _, ok := r.hole[m.player]; !ok {
+	_, joined := r.hole[m.player]
+	if !joined && r.meta.MaxPlayers > 0 && len(r.players) >= r.meta.MaxPlayers {
+		m.fail(newError("房间已满"))
+		return
+	}
+	if joined && m.verify && !r.auth.verifySession(m.player, m.session) {
+		m.fail(errInvalidSession)
+		return
+	}
+	if m.conn != nil {
+		r.conn[m.player] = append(r.conn[m.player], m.conn)
+		m.conn.attach()
+	}
+	r.lang[m.player] = m.lang
+	ret := &JoinResult{}
+	if !joined {
 		r.players = append(r.players, m.player)
 		r.hole[m.player] = make(map[DeckCard]int)
+		ret.Session = r.auth.issueSession(m.player)
+		if r.host == "" {
+			r.host = m.player
+		}
+	}
+	if len(r.conn[m.player]) > 0 {
+		r.setPresence(m.player, PresenceOnline)
+	} else if _, ok := r.presence[m.player]; !ok {
+		r.setPresence(m.player, PresenceOffline)
+	}
+	r.markSeen(m.player, true)
+	r.touchExpiry()
+	r.handleWelcome(m.player, m.reqID, ret.Session)
+	if !joined {
+		r.fireScript("player_join", m.player)
+	}
+	if m.reply != nil {
+		m.reply <- r.makeResult(m.player, ret, nil)
 	}
-	r.handleWelcome(m.player)
 }
 
-func handle[T any](r *Room, player string, data json.RawMessage, f func(string, T)) {
+func handle[T any](msg *ClientMessage, f func(string, T) (any, error)) (any, error) {
 	var args T
-	if err := json.Unmarshal(data, &args); err != nil {
-		r.sendMsgTo(player, &ServerMessage{Type: "error", Data: "请求格式错误"})
-		return
+	if err := json.Unmarshal(msg.Data, &args); err != nil {
+		return nil, newError("请求格式错误")
+	}
+	return f(msg.Player, args)
+}
+
+func (r *Room) dispatch(msg *ClientMessage) (any, error) {
+	if msg.Command == "nop" {
+		return nil, nil
+	}
+	if _, ok := r.hole[msg.Player]; !ok {
+		return nil, newError("请先加入房间")
+	}
+	if msg.conn != nil && !SliceContains(r.conn[msg.Player], msg.conn) {
+		return nil, newError("请先加入房间")
+	}
+	if msg.verify && !r.auth.verifySession(msg.Player, msg.session) {
+		return nil, errInvalidSession
+	}
+	if err := r.checkPreconditions(msg.Expect); err != nil {
+		return nil, err
+	}
+	switch msg.Command {
+	case "state":
+		return nil, nil
+	case "draw":
+		return handle(msg, r.handleDraw)
+	case "deal":
+		return handle(msg, r.handleDeal)
+	case "announce":
+		return handle(msg, r.handleAnnounce)
+	case "collect":
+		return handle(msg, r.handleCollect)
+	case "all_collect":
+		return r.handleAllCollect(msg.Player)
+	case "discard_board":
+		return handle(msg, r.handleDiscardBoard)
+	case "discard_hole":
+		return handle(msg, r.handleDiscardHole)
+	case "add_deck":
+		return handle(msg, r.handleAddDeck)
+	case "remove_deck":
+		return handle(msg, r.handleRemoveDeck)
+	case "reconfigure_deck":
+		return handle(msg, r.handleReconfigureDeck)
+	case "move":
+		return handle(msg, r.handleMove)
+	case "batch":
+		return handle(msg, r.handleBatch)
+	case "sweep":
+		return handle(msg, r.handleSweep)
+	case "lock":
+		return handle(msg, r.handleLock)
+	case "unlock":
+		return handle(msg, r.handleUnlock)
+	case "withdraw":
+		return handle(msg, r.handleWithdraw)
+	case "exchange":
+		return handle(msg, r.handleExchange)
+	case "bet":
+		return handle(msg, r.handleBet)
+	case "award":
+		return handle(msg, r.handleAward)
+	case "totals":
+		return handle(msg, r.handleTotals)
+	case "roll":
+		return handle(msg, r.handleRoll)
+	case "reroll":
+		return handle(msg, r.handleReroll)
+	case "rotate":
+		return handle(msg, r.handleRotate)
+	case "raise":
+		return handle(msg, r.handleRaise)
+	case "lower":
+		return handle(msg, r.handleLower)
+	case "set_zone":
+		return handle(msg, r.handleSetZone)
+	case "remove_zone":
+		return handle(msg, r.handleRemoveZone)
+	case "reset":
+		return r.handleReset(msg.Player)
+	case "recall":
+		return handle(msg, r.handleRecall)
+	case "set_password":
+		return handle(msg, r.handleSetPassword)
+	case "create_invite":
+		return handle(msg, r.handleCreateInvite)
+	case "revoke_invites":
+		return r.handleRevokeInvites(msg.Player)
+	case "leave":
+		return handle(msg, r.handleLeave)
+	case "keep_alive":
+		return r.handleKeepAlive()
+	case "set_ttl":
+		return handle(msg, r.handleSetTTL)
+	case "close_room":
+		return r.handleCloseRoom(msg.Player)
+	case "kick":
+		return handle(msg, r.handleKick)
+	case "ban":
+		return handle(msg, r.handleBan)
+	case "unban":
+		return handle(msg, r.handleUnban)
+	case "set_script":
+		return handle(msg, r.handleSetScript)
+	case "get_script":
+		return r.handleGetScript(msg.Player)
+	case "apply_preset":
+		return handle(msg, r.handleApplyPreset)
+	case "save_preset":
+		return handle(msg, r.handleSavePreset)
+	default:
+		return nil, newError("未知的指令：%s", msg.Command)
 	}
-	f(player, args)
 }
 
 func (r *Room) handleCommand(name string) {
-	ticker := time.NewTicker(30 * time.Minute)
-	defer func() {
-		logrus.Infof("room '%s' expired", name)
-		ticker.Stop()
-		RemoveRoom(name)
-		close(r.cmdChan)
-	}()
+	ticker := time.NewTicker(presenceCheckInterval)
+	defer ticker.Stop()
 	logrus.Infof("created new room '%s'", name)
+	r.touchExpiry()
 	for {
 		select {
 		case quit := <-r.quitChan:
@@ -131,36 +380,33 @@ func (r *Room) handleCommand(name string) {
 		case join := <-r.joinChan:
 			r.handleJoin(join)
 		case msg := <-r.cmdChan:
-			switch msg.Command {
-			case "draw":
-				handle(r, msg.Player, msg.Data, r.handleDraw)
-			case "announce":
-				handle(r, msg.Player, msg.Data, r.handleAnnounce)
-			case "collect":
-				handle(r, msg.Player, msg.Data, r.handleCollect)
-			case "all_collect":
-				r.handleAllCollect(msg.Player)
-			case "discard_board":
-				handle(r, msg.Player, msg.Data, r.handleDiscardBoard)
-			case "discard_hole":
-				handle(r, msg.Player, msg.Data, r.handleDiscardHole)
-			case "add_deck":
-				handle(r, msg.Player, msg.Data, r.handleAddDeck)
-			case "move":
-				handle(r, msg.Player, msg.Data, r.handleMove)
-			case "reset":
-				r.handleReset()
+			r.markSeen(msg.Player, msg.Command != "nop")
+			result, err := r.dispatch(msg)
+			r.fireCommandHooks(msg, err)
+			r.reply(msg, result, err)
+			if msg.Command != "nop" {
+				r.touchExpiry()
+			}
+			if r.closeReason != "" {
+				logrus.Infof("room '%s' closed by host", name)
+				r.shutdown(r.closeReason)
+				return
 			}
-			ticker.Reset(30 * time.Minute)
 		case <-ticker.C:
-			return
+			r.checkPresence()
+			if r.checkExpiry() {
+				logrus.Infof("room '%s' expired", name)
+				r.shutdown("expired")
+				return
+			}
 		}
 	}
 }
 
-func initStatic(r *gin.Engine, public fs.FS) {
+func initStatic(r *gin.Engine, g *gin.RouterGroup, public fs.FS) {
+	assetsPath := g.BasePath() + "/assets/"
 	r.Use(func(c *gin.Context) {
-		if strings.HasPrefix(c.Request.RequestURI, "/assets/") {
+		if strings.HasPrefix(c.Request.RequestURI, assetsPath) {
 			c.Header("Cache-Control", "public, max-age=15552000")
 		}
 	})
@@ -168,8 +414,8 @@ func initStatic(r *gin.Engine, public fs.FS) {
 	if err != nil {
 		panic(err)
 	}
-	r.StaticFS("/assets/", http.FS(assets))
-	r.StaticFileFS("/favicon.ico", "./favicon.ico", http.FS(public))
+	g.StaticFS("/assets/", http.FS(assets))
+	g.StaticFileFS("/favicon.ico", "./favicon.ico", http.FS(public))
 	r.NoRoute(func(c *gin.Context) {
 		if c.Request.Method != "GET" && c.Request.Method != "POST" {
 			c.Status(405)
@@ -188,29 +434,72 @@ func initStatic(r *gin.Engine, public fs.FS) {
 	})
 }
 
-func Run(public fs.FS) {
-	gin.SetMode(gin.ReleaseMode)
+func checkOrigin(origins []string) func(r *http.Request) bool {
+	if len(origins) == 0 {
+		return nil
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, allowed := range origins {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return true
+			}
+		}
+		logrus.Warnf("rejected WebSocket handshake from origin %s", origin)
+		return false
+	}
+}
+
+func Run(public fs.FS, cfg *Config) {
+	cfg.apply()
+	loadPresets()
+	if logrus.IsLevelEnabled(logrus.DebugLevel) {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+	}
 	r := gin.Default()
-	initStatic(r, public)
-	upgrader := websocket.Upgrader{}
-	r.GET("/ws", func(c *gin.Context) {
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		panic(err)
+	}
+	g := r.Group(cfg.BasePath)
+	initStatic(r, g, public)
+	upgrader := websocket.Upgrader{CheckOrigin: checkOrigin(cfg.AllowedOrigins)}
+	g.GET("/ws", func(c *gin.Context) {
 		logrus.Infof("handshake from %s", c.ClientIP())
-		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if !quota.acquireConn(c.ClientIP()) {
+			logrus.Warnf("rejected handshake from %s: too many connections", c.ClientIP())
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+		defer quota.releaseConn(c.ClientIP())
+		ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
 			logrus.Errorf("WebSocket upgrade failed: %+v", err)
 			return
 		}
-		defer func() {
-			if e := conn.Close(); e != nil {
-				logrus.Errorf("failed to close WebSocket connection from %s: %+v", c.ClientIP(), e)
-			} else {
-				logrus.Infof("WebSocket connection from %s closed", c.ClientIP())
-			}
-		}()
-		handleClientMessage(c.ClientIP(), conn)
+		ws.SetReadLimit(cfg.MaxMessageSize)
+		conn := newConn(ws, c.ClientIP())
+		go conn.writeLoop()
+		defer conn.Close()
+		handleClientMessage(c.ClientIP(), NegotiateLang(c.GetHeader("Accept-Language")), conn)
+	})
+	g.GET("/rooms", func(c *gin.Context) {
+		c.JSON(http.StatusOK, ListRooms())
 	})
-	logrus.Infof("listening on :6700")
-	if err := r.Run(":6700"); err != nil {
+	initAPI(g)
+	var err error
+	if cfg.TLSCert != "" {
+		logrus.Infof("listening on %s (TLS)", cfg.Listen)
+		err = r.RunTLS(cfg.Listen, cfg.TLSCert, cfg.TLSKey)
+	} else {
+		logrus.Infof("listening on %s", cfg.Listen)
+		err = r.Run(cfg.Listen)
+	}
+	if err != nil {
 		panic(err)
 	}
 }
@@ -220,9 +509,38 @@ type playerRoomPair struct {
 	player string
 }
 
-func handleClientMessage(ip string, conn *websocket.Conn) {
+func hasJoined(joined []*playerRoomPair, room, player string) bool {
+	for _, pair := range joined {
+		if pair.room == room && pair.player == player {
+			return true
+		}
+	}
+	return false
+}
+
+type joinArgs struct {
+	Lang     string `json:"lang"`
+	Password string `json:"password"`
+	Invite   string `json:"invite"`
+	Session  string `json:"session"`
+}
+
+type createRoomArgs struct {
+	joinArgs
+	RoomMeta
+	CreateInvite bool `json:"create_invite"`
+}
+
+func writeError(conn *Conn, lang, reqID, format string, args ...any) {
+	conn.SendJSON(&ServerMessage{Type: "error", ReqID: reqID, Data: Tr(lang, format, args...)})
+}
+
+func handleClientMessage(ip, lang string, conn *Conn) {
 	var mid uint64 = 0
+	var ok bool
 	var joined []*playerRoomPair
+	limiter := newRateLimiter(config.RateLimit, config.RateBurst)
+	heartbeat := newRateLimiter(heartbeatRate, heartbeatBurst)
 	defer func() {
 		for _, pair := range joined {
 			room, ok := GetRoom(pair.room)
@@ -233,53 +551,117 @@ func handleClientMessage(ip string, conn *websocket.Conn) {
 		}
 	}()
 	for {
-		_, m, err := conn.ReadMessage()
+		_, m, err := conn.ws.ReadMessage()
 		if err != nil {
-			if !websocket.IsCloseError(err) {
+			if !conn.Closed() && !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
 				logrus.Errorf("read msg from %s failed: %+v", ip, err)
-				_ = conn.WriteJSON(&ServerMessage{Type: "fatal", Data: err.Error()})
+				conn.SendJSON(&ServerMessage{Type: "fatal", Data: err.Error()})
 			}
 			break
 		}
 		var msg ClientMessage
 		if err = json.Unmarshal(m, &msg); err != nil {
 			logrus.Errorf("unmarshal msg from %s failed: %+v", ip, err)
-			_ = conn.WriteJSON(&ServerMessage{Type: "error", Data: "无效的请求"})
+			writeError(conn, lang, "", "无效的请求")
 			continue
 		}
 		if msg.Command == "nop" {
+			if !heartbeat.allow() {
+				continue
+			}
+			if room, ok := GetRoom(msg.Room); ok && hasJoined(joined, msg.Room, msg.Player) {
+				msg.conn = conn
+				_ = room.PushRequest(&msg)
+			}
+			continue
+		}
+		if !limiter.allow() {
+			logrus.Warnf("throttled msg from %s, player=%s, room=%s, cmd=%s", ip, msg.Player, msg.Room, msg.Command)
+			conn.SendJSON(&ServerMessage{Type: "throttled", ReqID: msg.ReqID, Data: Tr(lang, "请求过于频繁，请稍后再试")})
 			continue
 		}
 		msg.ID = mid
 		mid++
 		logrus.Infof("recv msg player=%s, room=%s, mid=%d, cmd=%s, data=%s", msg.Player, msg.Room, msg.ID, msg.Command, string(msg.Data))
+		if msg.Command == "list_rooms" {
+			conn.SendJSON(&ServerMessage{Type: "rooms", ReqID: msg.ReqID, Data: ListRooms()})
+			continue
+		}
+		if msg.Command == "list_presets" {
+			conn.SendJSON(&ServerMessage{Type: "presets", ReqID: msg.ReqID, Data: ListPresets(lang)})
+			continue
+		}
 		if msg.Command == "download" {
 			var req downloadRequest
 			if err = json.Unmarshal(msg.Data, &req); err != nil {
 				logrus.Errorf("failed to unmarshal download req(mid=%d) from %s: %+v", msg.ID, ip, err)
-				_ = conn.WriteJSON(&ServerMessage{Type: "error", Data: "请求错误"})
+				writeError(conn, lang, msg.ReqID, "请求错误")
 				continue
 			}
-			handleDownload(req, conn)
+			handleDownload(req, lang, msg.ReqID, conn)
 			continue
 		}
 		if msg.Room == "" || msg.Player == "" {
-			_ = conn.WriteJSON(&ServerMessage{Type: "error", Data: "房间名和玩家名不能为空"})
+			writeError(conn, lang, msg.ReqID, "房间名和玩家名不能为空")
 			continue
 		}
-		if msg.Command == "join" {
-			room := GetOrCreateRoom(msg.Room)
-			joined = append(joined, &playerRoomPair{room: msg.Room, player: msg.Room})
-			room.PushSubscribe(msg.Player, conn)
+		if msg.Command == "join" || msg.Command == "create_room" {
+			var args createRoomArgs
+			if len(msg.Data) > 0 {
+				if err = json.Unmarshal(msg.Data, &args); err != nil {
+					writeError(conn, lang, msg.ReqID, "请求格式错误")
+					continue
+				}
+			}
+			if l, ok := NormalizeLang(args.Lang); ok {
+				lang = l
+			}
+			var room *Room
+			if msg.Command == "create_room" {
+				room, err = CreateRoom(msg.Room, msg.Player, ip, args.RoomMeta, args.Password)
+				if err != nil {
+					writeError(conn, lang, msg.ReqID, "%s", LocalizeError(err, lang))
+					continue
+				}
+				logrus.Infof("player %s from %s created room '%s'", msg.Player, ip, msg.Room)
+			} else if room, ok = GetRoom(msg.Room); !ok {
+				writeError(conn, lang, msg.ReqID, "房间不存在")
+				continue
+			} else if err = room.authorizeFrom(ip, msg.Player, args.Password, args.Invite); err != nil {
+				logrus.Warnf("player %s from %s failed to join room '%s': %v", msg.Player, ip, msg.Room, err)
+				writeError(conn, lang, msg.ReqID, "%s", LocalizeError(err, lang))
+				continue
+			}
+			if !hasJoined(joined, msg.Room, msg.Player) {
+				joined = append(joined, &playerRoomPair{room: msg.Room, player: msg.Player})
+			}
+			if msg.Command == "create_room" && args.CreateInvite {
+				token, expiresAt := room.auth.createInvite(msg.Room, defaultInviteTTL)
+				conn.SendJSON(&ServerMessage{Type: "invite", ReqID: msg.ReqID, Data: &InviteResponse{
+					Room:      msg.Room,
+					Token:     token,
+					ExpiresAt: expiresAt,
+				}})
+			}
+			if !room.PushSubscribe(msg.Player, lang, msg.ReqID, args.Session, conn) {
+				writeError(conn, lang, msg.ReqID, "房间不存在")
+			}
+			continue
+		}
+		if !hasJoined(joined, msg.Room, msg.Player) {
+			writeError(conn, lang, msg.ReqID, "请先加入房间")
 			continue
 		}
 		room, ok := GetRoom(msg.Room)
 		if !ok {
 			logrus.Errorf("failed to handle command(player=%s, room=%s, mid=%d, cmd=%s): room not exists", msg.Player, msg.Room, msg.ID, msg.Command)
-			_ = conn.WriteJSON(&ServerMessage{Type: "error", Data: "房间不存在"})
+			writeError(conn, lang, msg.ReqID, "房间不存在")
 			continue
 		}
-		room.PushRequest(&msg)
+		msg.conn = conn
+		if !room.PushRequest(&msg) {
+			writeError(conn, lang, msg.ReqID, "房间不存在")
+		}
 	}
 }
 
@@ -293,13 +675,13 @@ type downloadResponse struct {
 	HTML string `json:"html"`
 }
 
-func handleDownload(req downloadRequest, conn *websocket.Conn) {
+func handleDownload(req downloadRequest, lang, reqID string, conn *Conn) {
 	h, ok := GetCardHTML(req.Deck, req.Card)
 	if !ok {
-		_ = conn.WriteJSON(&ServerMessage{Type: "error", Data: fmt.Sprintf("不存在的模型：%s@%s", req.Deck, req.Card)})
+		writeError(conn, lang, reqID, "不存在的模型：%s@%s", req.Deck, req.Card)
 		return
 	}
-	_ = conn.WriteJSON(&ServerMessage{Type: "download", Data: downloadResponse{
+	conn.SendJSON(&ServerMessage{Type: "download", ReqID: reqID, Data: downloadResponse{
 		downloadRequest: req,
 		HTML:            h,
 	}})
@@ -18,6 +18,10 @@ type Deck interface {
 	Draw(count int) []Card
 }
 
+type Renamable interface {
+	Rename(name string)
+}
+
 type DeckCard struct {
 	DeckId int
 	Card
@@ -44,9 +48,14 @@ func (d DeckCard) MarshalText() ([]byte, error) {
 }
 
 type PublicCard struct {
-	Card DeckCard `json:"card"`
-	X    float32  `json:"x"`
-	Y    float32  `json:"y"`
-	OpID uint     `json:"op_id"`
-	PlID uint     `json:"pl_id"`
+	Card     DeckCard `json:"card"`
+	X        float32  `json:"x"`
+	Y        float32  `json:"y"`
+	OpID     uint     `json:"op_id"`
+	PlID     uint     `json:"pl_id"`
+	Z        int      `json:"z"`
+	Rotation int      `json:"rotation"`
+	Zone     string   `json:"zone,omitempty"`
+	Owner    string   `json:"owner,omitempty"`
+	Hidden   bool     `json:"hidden,omitempty"`
 }
@@ -0,0 +1,55 @@
+package sim_board
+
+type Preconditions struct {
+	Room  *uint64           `json:"room"`
+	Decks map[int]uint64    `json:"decks"`
+	Hands map[string]uint64 `json:"hands"`
+}
+
+type ConflictResponse struct {
+	Message string             `json:"message"`
+	State   *BroadcastResponse `json:"state"`
+}
+
+type conflictError struct {
+	localizedError
+}
+
+func newConflict(format string, args ...any) error {
+	return &conflictError{localizedError{format: format, args: args}}
+}
+
+func (r *Room) touchDeck(id int) {
+	if d, ok := r.deck(id); ok {
+		d.version++
+	}
+}
+
+func (r *Room) touchHand(player string) {
+	r.handVersions[player]++
+	r.scriptTouchHand(player)
+}
+
+func (r *Room) checkPreconditions(p *Preconditions) error {
+	if p == nil {
+		return nil
+	}
+	if p.Room != nil && *p.Room != r.version {
+		return newConflict("房间状态已变更, expect=%d, current=%d", *p.Room, r.version)
+	}
+	for id, v := range p.Decks {
+		d, ok := r.deck(id)
+		if !ok {
+			return newConflict("牌堆不存在")
+		}
+		if d.version != v {
+			return newConflict("牌堆状态已变更, deck=%d, expect=%d, current=%d", id, v, d.version)
+		}
+	}
+	for player, v := range p.Hands {
+		if r.handVersions[player] != v {
+			return newConflict("手牌状态已变更, player=%s, expect=%d, current=%d", player, v, r.handVersions[player])
+		}
+	}
+	return nil
+}
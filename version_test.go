@@ -0,0 +1,76 @@
+package sim_board
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPreconditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		expect   func(state *BroadcastResponse, deck int) *Preconditions
+		conflict bool
+	}{
+		{"none", func(*BroadcastResponse, int) *Preconditions { return nil }, false},
+		{"current room", func(s *BroadcastResponse, _ int) *Preconditions {
+			v := s.Version
+			return &Preconditions{Room: &v}
+		}, false},
+		{"stale room", func(s *BroadcastResponse, _ int) *Preconditions {
+			v := s.Version - 1
+			return &Preconditions{Room: &v}
+		}, true},
+		{"current deck", func(s *BroadcastResponse, deck int) *Preconditions {
+			return &Preconditions{Decks: map[int]uint64{deck: s.Decks[0].Version}}
+		}, false},
+		{"stale deck", func(s *BroadcastResponse, deck int) *Preconditions {
+			return &Preconditions{Decks: map[int]uint64{deck: s.Decks[0].Version + 1}}
+		}, true},
+		{"missing deck", func(s *BroadcastResponse, deck int) *Preconditions {
+			return &Preconditions{Decks: map[int]uint64{deck + 1: 0}}
+		}, true},
+		{"current hand", func(s *BroadcastResponse, _ int) *Preconditions {
+			return &Preconditions{Hands: map[string]uint64{"bob": s.HandVersions["bob"]}}
+		}, false},
+		{"stale hand", func(s *BroadcastResponse, _ int) *Preconditions {
+			return &Preconditions{Hands: map[string]uint64{"bob": s.HandVersions["bob"] + 1}}
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRoom(t, "alice", "bob")
+			deck := testAddDeck(t, r, 10)
+			if _, err := testDo(r, "alice", "draw", DrawArgs{Deck: deck, Num: 1, Target: "bob"}); err != nil {
+				t.Fatal(err)
+			}
+			before := testState(t, r, "bob")
+			data, _ := json.Marshal(DrawArgs{Deck: deck, Num: 1, Target: "bob"})
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_, err := r.Request(ctx, &ClientMessage{Command: "draw", Player: "alice", Room: r.name, Data: data, Expect: tt.expect(before, deck)})
+			if IsConflict(err) != tt.conflict {
+				t.Fatalf("err = %v, want conflict %v", err, tt.conflict)
+			}
+			if !tt.conflict && err != nil {
+				t.Fatal(err)
+			}
+			after := testState(t, r, "bob")
+			want := 2
+			if tt.conflict {
+				want = 1
+			}
+			n := 0
+			for _, c := range after.Hole {
+				n += c
+			}
+			if n != want {
+				t.Errorf("bob holds %d cards, want %d", n, want)
+			}
+			if tt.conflict && (after.Version != before.Version || after.Decks[0].Version != before.Decks[0].Version || after.HandVersions["bob"] != before.HandVersions["bob"]) {
+				t.Errorf("versions changed by a rejected command")
+			}
+		})
+	}
+}
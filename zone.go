@@ -0,0 +1,268 @@
+package sim_board
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxZones      = 64
+	freeSpotSteps = 40
+)
+
+type ZoneVisibility string
+
+const (
+	ZonePublic   ZoneVisibility = "public"
+	ZoneOwner    ZoneVisibility = "owner"
+	ZoneFaceDown ZoneVisibility = "face_down"
+)
+
+type Zone struct {
+	ID         string         `json:"id"`
+	Title      string         `json:"title,omitempty"`
+	X          float32        `json:"x"`
+	Y          float32        `json:"y"`
+	W          float32        `json:"w"`
+	H          float32        `json:"h"`
+	Owner      string         `json:"owner,omitempty"`
+	Visibility ZoneVisibility `json:"visibility"`
+	Capacity   int            `json:"capacity"`
+}
+
+func (z *Zone) contains(x, y float32) bool {
+	return x >= z.X && x <= z.X+z.W && y >= z.Y && y <= z.Y+z.H
+}
+
+func (z *Zone) visibleTo(player string) bool {
+	switch z.Visibility {
+	case ZoneOwner:
+		return player == z.Owner
+	case ZoneFaceDown:
+		return false
+	}
+	return true
+}
+
+type ZoneState struct {
+	*Zone
+	Cards []string `json:"cards"`
+}
+
+func getRandomPos(z *Zone) (float32, float32) {
+	if z == nil {
+		x := rand.Float32()/2.0 + 0.25
+		y := rand.Float32()/2.0 + 0.25
+		return x, y
+	}
+	x := z.X + z.W*(rand.Float32()/2.0+0.25)
+	y := z.Y + z.H*(rand.Float32()/2.0+0.25)
+	return x, y
+}
+
+func (r *Room) zone(id string) (*Zone, bool) {
+	for _, z := range r.zones {
+		if z.ID == id {
+			return z, true
+		}
+	}
+	return nil, false
+}
+
+func (r *Room) zoneAt(x, y float32) *Zone {
+	for i := len(r.zones) - 1; i >= 0; i-- {
+		if r.zones[i].contains(x, y) {
+			return r.zones[i]
+		}
+	}
+	return nil
+}
+
+func (r *Room) zoneFree(z *Zone) int {
+	if z.Capacity <= 0 {
+		return -1
+	}
+	n := z.Capacity
+	for _, card := range r.board {
+		if card.Zone == z.ID {
+			n--
+		}
+	}
+	return max(n, 0)
+}
+
+func (r *Room) checkZone(z *Zone, num int) error {
+	if z == nil {
+		return nil
+	}
+	if free := r.zoneFree(z); free >= 0 && num > free {
+		return newError("区域已满：%s", z.ID)
+	}
+	return nil
+}
+
+// freeSpot returns the point nearest to (x, y) that is not inside a full zone,
+// and the zone it falls in. If the whole board is covered by full zones the
+// card stays at (x, y) and overfills the zone there.
+func (r *Room) freeSpot(x, y float32) (float32, float32, *Zone) {
+	z := r.zoneAt(x, y)
+	if z == nil || r.zoneFree(z) != 0 {
+		return x, y, z
+	}
+	full := make(map[*Zone]bool)
+	bx, by, bz, best := x, y, z, float32(-1)
+	for i := 0; i <= freeSpotSteps; i++ {
+		for j := 0; j <= freeSpotSteps; j++ {
+			px, py := float32(i)/freeSpotSteps, float32(j)/freeSpotSteps
+			pz := r.zoneAt(px, py)
+			if pz != nil {
+				if _, ok := full[pz]; !ok {
+					full[pz] = r.zoneFree(pz) == 0
+				}
+				if full[pz] {
+					continue
+				}
+			}
+			if d := (px-x)*(px-x) + (py-y)*(py-y); best < 0 || d < best {
+				bx, by, bz, best = px, py, pz, d
+			}
+		}
+	}
+	return bx, by, bz
+}
+
+func (r *Room) placeCard(card DeckCard, x, y float32) (string, *PublicCard) {
+	x, y, z := r.freeSpot(min(max(x, 0), 1), min(max(y, 0), 1))
+	return r.placeCardIn(card, x, y, z)
+}
+
+func (r *Room) placeCardIn(card DeckCard, x, y float32, z *Zone) (string, *PublicCard) {
+	id := uuid.NewString()
+	pc := &PublicCard{
+		Card: card,
+		X:    x,
+		Y:    y,
+		OpID: 0,
+		PlID: r.placeCnter,
+		Z:    r.topZ(),
+	}
+	if z != nil {
+		pc.Zone = z.ID
+	}
+	r.placeCnter++
+	r.board[id] = pc
+	return id, pc
+}
+
+func (r *Room) viewCard(player string, card *PublicCard) *PublicCard {
+	if z, ok := r.zone(card.Zone); ok && !z.visibleTo(player) {
+		hidden := *card
+		hidden.Card.Card = ""
+		hidden.Hidden = true
+		return &hidden
+	}
+	return card
+}
+
+func (r *Room) makeBoard(player string) (map[string]*PublicCard, []ZoneState) {
+	if len(r.zones) == 0 {
+		return r.board, nil
+	}
+	board, copied := r.board, false
+	zones := make([]ZoneState, 0, len(r.zones))
+	index := make(map[string]int, len(r.zones))
+	for i, z := range r.zones {
+		index[z.ID] = i
+		zones = append(zones, ZoneState{Zone: z, Cards: []string{}})
+	}
+	for id, card := range r.board {
+		i, ok := index[card.Zone]
+		if !ok {
+			continue
+		}
+		zones[i].Cards = append(zones[i].Cards, id)
+		if !r.zones[i].visibleTo(player) {
+			if !copied {
+				board = make(map[string]*PublicCard, len(r.board))
+				for k, v := range r.board {
+					board[k] = v
+				}
+				copied = true
+			}
+			board[id] = r.viewCard(player, card)
+		}
+	}
+	for _, z := range zones {
+		sort.Slice(z.Cards, func(i, j int) bool {
+			a, b := r.board[z.Cards[i]], r.board[z.Cards[j]]
+			if a.Z != b.Z {
+				return a.Z < b.Z
+			}
+			return a.PlID < b.PlID
+		})
+	}
+	return board, zones
+}
+
+func (r *Room) handleSetZone(player string, args Zone) (any, error) {
+	if err := r.requireHost(player); err != nil {
+		return nil, err
+	}
+	if args.ID == "" {
+		return nil, newError("区域名不能为空")
+	}
+	if args.W <= 0 || args.H <= 0 || args.X < 0 || args.Y < 0 || args.X+args.W > 1 || args.Y+args.H > 1 {
+		return nil, newError("区域范围无效")
+	}
+	switch args.Visibility {
+	case "":
+		args.Visibility = ZonePublic
+	case ZonePublic, ZoneOwner, ZoneFaceDown:
+	default:
+		return nil, newError("未知的区域可见性：%s", args.Visibility)
+	}
+	if args.Owner != "" && !SliceContains(r.players, args.Owner) {
+		return nil, newError("目标不存在")
+	}
+	if args.Capacity < 0 {
+		return nil, newError("区域容量无效")
+	}
+	z := &args
+	if old, ok := r.zone(args.ID); ok {
+		*old = args
+		z = old
+	} else {
+		if len(r.zones) >= maxZones {
+			return nil, newError("区域数量已达上限")
+		}
+		r.zones = append(r.zones, z)
+	}
+	r.broadcast()
+	return z, nil
+}
+
+type ZoneArgs struct {
+	ID string `json:"id"`
+}
+
+func (r *Room) handleRemoveZone(player string, args ZoneArgs) (any, error) {
+	if err := r.requireHost(player); err != nil {
+		return nil, err
+	}
+	for i, z := range r.zones {
+		if z.ID != args.ID {
+			continue
+		}
+		r.zones = append(r.zones[:i], r.zones[i+1:]...)
+		for _, card := range r.board {
+			if card.Zone == args.ID {
+				card.Zone = ""
+			}
+		}
+		r.broadcast()
+		return nil, nil
+	}
+	return nil, newError("区域不存在")
+}
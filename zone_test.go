@@ -0,0 +1,37 @@
+package sim_board
+
+import (
+	"testing"
+)
+
+func TestPlaceCardAvoidsFullZones(t *testing.T) {
+	r := newTestRoom(t, "alice", "bob")
+	zone := &Zone{ID: "z", X: 0.2, Y: 0.2, W: 0.6, H: 0.6, Owner: "alice", Visibility: ZoneOwner, Capacity: 1}
+	if _, err := testDo(r, "alice", "set_zone", zone); err != nil {
+		t.Fatal(err)
+	}
+	deck := testAddDeck(t, r, 10)
+	testPlace(t, r, deck, 1, "z")
+	if _, err := testDo(r, "alice", "draw", DrawArgs{Deck: deck, Num: 3, Target: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testDo(r, "bob", "leave", LeaveArgs{Policy: HandPolicyBoard}); err != nil {
+		t.Fatal(err)
+	}
+	testPlace(t, r, deck, 2, "")
+	board := testState(t, r, "alice").Board
+	if len(board) != 6 {
+		t.Fatalf("%d cards on the board, want 6", len(board))
+	}
+	inZone := 0
+	for id, card := range board {
+		if card.Zone == zone.ID {
+			inZone++
+		} else if zone.contains(card.X, card.Y) {
+			t.Errorf("card %s landed in the full zone at (%v, %v)", id, card.X, card.Y)
+		}
+	}
+	if inZone != 1 {
+		t.Errorf("%d cards in the zone, want 1", inZone)
+	}
+}